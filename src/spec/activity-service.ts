@@ -42,6 +42,7 @@ export class EncodeResult {
   fileName: string
   fileType: string
   filesBytes: Uint8Array[]
+  filledGaps?: FilledGap[][] // gaps filled with interpolated records of each file, only if fillGaps is specified
 
   constructor(data?: any) {
     const casted = data as EncodeResult
@@ -53,9 +54,264 @@ export class EncodeResult {
     this.fileName = casted?.fileName
     this.fileType = casted?.fileType
     this.filesBytes = casted?.filesBytes
+    this.filledGaps = casted?.filledGaps
   }
 }
 
+export class FilledGap {
+  startTime: string = '' // the first interpolated record
+  endTime: string = '' // the last interpolated record
+  records: number = 0
+}
+
+export class AnalyzeResult {
+  err: string | null = null
+  sessions: Analysis[] = []
+  overall: Analysis = new Analysis()
+  activityLoads: ActivityLoad[] = []
+  trainingLoads: DailyTrainingLoad[] = []
+  analyzeTook: number = 0
+
+  constructor(json?: any) {
+    const casted = json as AnalyzeResult
+    this.err = casted?.err
+    this.sessions = casted?.sessions
+    this.overall = casted?.overall
+    this.activityLoads = casted?.activityLoads
+    this.trainingLoads = casted?.trainingLoads
+    this.analyzeTook = casted?.analyzeTook
+  }
+}
+
+export class Analysis {
+  meanMaxPower: MeanMaxPoint[] = []
+  meanMaxHeartRate: MeanMaxPoint[] = []
+  bestEfforts: BestEffort[] = []
+}
+
+export class MeanMaxPoint {
+  duration: number = 0 // in seconds
+  value: number | null = null
+}
+
+export class BestEffort {
+  distance: number = 0 // in meters
+  duration?: number // in seconds, undefined if the distance is never covered
+  startTime?: string
+}
+
+export class ActivityLoad {
+  startTime?: string
+  method?: 'tss' | 'trimp' | 'mixed' // undefined if the load is not available
+  load?: number
+}
+
+export class DailyTrainingLoad {
+  date: string = '' // YYYY-MM-DD
+  load: number = 0
+  atl: number = 0 // Acute Training Load (fatigue)
+  ctl: number = 0 // Chronic Training Load (fitness)
+  tsb: number = 0 // Training Stress Balance (form)
+}
+
+export class MatchSegmentsResult {
+  err: string | null = null
+  segments: SegmentMatch[] = []
+  matchSegmentsTook: number = 0
+
+  constructor(json?: any) {
+    const casted = json as MatchSegmentsResult
+    this.err = casted?.err
+    this.segments = casted?.segments
+    this.matchSegmentsTook = casted?.matchSegmentsTook
+  }
+}
+
+export class SegmentMatch {
+  name: string = ''
+  length: number = 0 // in meters
+  efforts: SegmentEffort[] = [] // sorted by elapsedTime, the fastest first
+}
+
+export class SegmentEffort {
+  activityIndex: number = 0
+  sessionIndex: number = 0
+  startIndex: number = 0 // index of session's records
+  endIndex: number = 0
+  startTime?: string
+  elapsedTime: number = 0 // in seconds
+  distance: number = 0 // in meters
+  avgPower?: number
+  avgHeartRate?: number
+}
+
+// Segment is written into a segment library file (GPX routes) by encodeSegments.
+export class Segment {
+  name: string = ''
+  points: [number, number][] = [] // [lat, long], the first and the last are the start and the end
+  radius?: number = 0 // in meters around the points, 0 means the radius passed to matchSegments or the default (25m)
+}
+
+export class CompareResult {
+  err: string | null = null
+  sessions: CompareSession[] = [] // matching comparison.attempts
+  comparison: Comparison = new Comparison()
+  compareTook: number = 0
+
+  constructor(json?: any) {
+    const casted = json as CompareResult
+    this.err = casted?.err
+    this.sessions = casted?.sessions
+    this.comparison = casted?.comparison
+    this.compareTook = casted?.compareTook
+  }
+}
+
+// Comparison: the first attempt is the reference, every attempt's arrays match distances.
+export class Comparison {
+  distances: number[] = [] // in meters
+  attempts: AttemptComparison[] = []
+}
+
+export class AttemptComparison {
+  elapsedTimes: (number | null)[] = [] // in seconds
+  timeGaps: (number | null)[] = [] // in seconds behind the reference, negative means ahead
+  speeds: (number | null)[] = [] // in m/s over the preceding interval
+  speedDiffs: (number | null)[] = []
+  heartRates: (number | null)[] = []
+  heartRateDiffs: (number | null)[] = []
+  distanceGaps: (number | null)[] = [] // virtual partner: meters ahead of the reference at the same elapsed time
+}
+
+export class CompareSpecifications {
+  sessions: CompareSession[] = [] // the first is the reference, at least 2 sessions
+  alignment: Alignment = Alignment.Distance
+  interval: number = 0 // in meters, 0 means default (100m), at least 1m and widened to at most 10000 samples
+
+  constructor(data?: CompareSpecifications) {
+    this.sessions = data?.sessions ?? []
+    this.alignment = data?.alignment ?? Alignment.Distance
+    this.interval = data?.interval ?? 0
+  }
+}
+
+export class CompareSession {
+  activityIndex: number = 0
+  sessionIndex: number = 0
+}
+
+export enum Alignment {
+  Distance = 'distance',
+  Position = 'position'
+}
+
+export class AggregateResult {
+  err: string | null = null
+  activities: number = 0
+  heatmap: Heatmap = new Heatmap()
+  sportTotals: SportTotal[] = [] // sorted by sport
+  failures: AggregateFailure[] = [] // files that could not be decoded and are skipped, sorted by index
+  aggregateTook: number = 0
+
+  constructor(json?: any) {
+    const casted = json as AggregateResult
+    this.err = casted?.err
+    this.activities = casted?.activities
+    this.heatmap = casted?.heatmap
+    this.sportTotals = casted?.sportTotals
+    this.failures = casted?.failures
+    this.aggregateTook = casted?.aggregateTook
+  }
+}
+
+export class AggregateFailure {
+  index: number = 0 // index of the file in the input
+  err: string = ''
+}
+
+// Heatmap: a cell is a pixel of the world map at the zoom level, the cell is in tile (x / tileSize, y / tileSize).
+export class Heatmap {
+  zoom: number = 0 // 14 by default, at most 16
+  tileSize: number = 256
+  cells: number[] = [] // flat [x, y, count, x, y, count, ...], count is the number of passes through the cell
+}
+
+export class SportTotal {
+  sport: string = ''
+  activities: number = 0
+  sessions: number = 0
+  distance: number = 0 // in meters
+  elapsedTime: number = 0 // in seconds
+  movingTime: number = 0 // in seconds
+  ascent: number = 0 // in meters
+}
+
+export class ReportResult {
+  err: string | null = null
+  period: ReportPeriod = ReportPeriod.Week
+  sports: ReportGroup[] = [] // sorted by sport
+  periods: ReportGroup[] = [] // sorted by period then sport
+  reportTook: number = 0
+
+  constructor(json?: any) {
+    const casted = json as ReportResult
+    this.err = casted?.err
+    this.period = casted?.period
+    this.sports = casted?.sports
+    this.periods = casted?.periods
+    this.reportTook = casted?.reportTook
+  }
+}
+
+export class ReportGroup {
+  sport: string = ''
+  period?: string // YYYY-MM-DD, Monday of the week or the first day of the month; undefined in sports
+  sessions: number = 0
+  totalDistance?: number // in meters
+  totalMovingTime?: number // in seconds
+  totalElapsedTime?: number // in seconds
+  totalAscent?: number // in meters
+  totalCalories?: number // in kcal
+  avgHeartRate?: number // weighted by moving time
+  avgPower?: number // weighted by moving time
+  longestDistance?: number // in meters
+  fastestAvgSpeed?: number // in m/s
+}
+
+// ReportSpecifications: format 'csv' returns { err, file } where file is the CSV content.
+export class ReportSpecifications {
+  period: ReportPeriod = ReportPeriod.Week
+  format: ReportFormat = ReportFormat.JSON
+
+  constructor(data?: ReportSpecifications) {
+    this.period = data?.period ?? ReportPeriod.Week
+    this.format = data?.format ?? ReportFormat.JSON
+  }
+}
+
+// ExportRecordsResult: file is the CSV content of every decoded session's records, a row for each record
+// including calculated grade, pace and W' balance.
+export class ExportRecordsResult {
+  err: string | null = null
+  file: string = ''
+
+  constructor(json?: any) {
+    const casted = json as ExportRecordsResult
+    this.err = casted?.err
+    this.file = casted?.file
+  }
+}
+
+export enum ReportPeriod {
+  Week = 'week',
+  Month = 'month'
+}
+
+export enum ReportFormat {
+  JSON = 'json',
+  CSV = 'csv'
+}
+
 export class EncodeSpecifications {
   toolMode: number = 0
   targetFileType: FileType = 0
@@ -64,8 +320,18 @@ export class EncodeSpecifications {
   deviceName: string = 'Unknown'
   sports: (string | null)[] = []
   trimMarkers?: Marker[] | null = []
+  autoTrim?: boolean = false // trim stationary head and tail of each session, trimMarkers are ignored
   concealMarkers?: Marker[] | null = []
   removeFields?: string[] | null = []
+  pauseRemoval?: PauseRemovalMethod = PauseRemovalMethod.Compress
+  useSmoothed?: boolean = false
+  simplify?: SimplifyMethod = SimplifyMethod.None
+  tolerance?: number = 0
+  interval?: number = 0
+  fillGaps?: GapFillMethod = GapFillMethod.None
+  fillGapsThreshold?: number = 0 // in seconds, 0 means default (10s)
+  preprocess?: PreprocessSettings | null = null // null uses the default settings, keeping the last athlete profile, without recalculating the activities
+  writeSplits?: boolean = false
 
   constructor(data: EncodeSpecifications) {
     this.toolMode = data.toolMode
@@ -75,16 +341,136 @@ export class EncodeSpecifications {
     this.deviceName = data.deviceName
     this.sports = data.sports
     this.trimMarkers = data.trimMarkers
+    this.autoTrim = data.autoTrim
     this.concealMarkers = data.concealMarkers
     this.removeFields = data.removeFields
+    this.pauseRemoval = data.pauseRemoval
+    this.useSmoothed = data.useSmoothed
+    this.simplify = data.simplify
+    this.tolerance = data.tolerance
+    this.interval = data.interval
+    this.fillGaps = data.fillGaps
+    this.fillGapsThreshold = data.fillGapsThreshold
+    this.preprocess = data.preprocess
+    this.writeSplits = data.writeSplits
+  }
+}
+
+export class PreprocessSettings {
+  smoothingElevationDistance: number = 0 // 0 means default (30m)
+  calculateGradeDistance: number = 0 // 0 means default (100m)
+  maxAcceleration: number = 0 // 0 means default (10m/s²)
+  smoothingPosition: boolean = false
+  processNoise: number = 0
+  measurementNoise: number = 0
+  toleranceMovingSpeeds: { [sport: string]: number } = {}
+  disabledStages: PreprocessStage[] = []
+  elevationGain: ElevationGain = ElevationGain.Smoothed
+  elevationGainThreshold: number = 0 // 0 means default (3m)
+  athlete: Athlete = new Athlete()
+  splitUnit: SplitUnit = SplitUnit.Kilometer
+  splitLength: number = 0 // in splitUnit, 0 means default (1)
+  minStopDuration: number = 0 // in seconds, 0 means default (60)
+
+  constructor(data?: PreprocessSettings) {
+    this.smoothingElevationDistance = data?.smoothingElevationDistance ?? 0
+    this.calculateGradeDistance = data?.calculateGradeDistance ?? 0
+    this.maxAcceleration = data?.maxAcceleration ?? 0
+    this.smoothingPosition = data?.smoothingPosition ?? false
+    this.processNoise = data?.processNoise ?? 0
+    this.measurementNoise = data?.measurementNoise ?? 0
+    this.toleranceMovingSpeeds = data?.toleranceMovingSpeeds ?? {}
+    this.disabledStages = data?.disabledStages ?? []
+    this.elevationGain = data?.elevationGain ?? ElevationGain.Smoothed
+    this.elevationGainThreshold = data?.elevationGainThreshold ?? 0
+    this.athlete = data?.athlete ?? new Athlete()
+    this.splitUnit = data?.splitUnit ?? SplitUnit.Kilometer
+    this.splitLength = data?.splitLength ?? 0
+    this.minStopDuration = data?.minStopDuration ?? 0
+  }
+}
+
+export enum SplitUnit {
+  Kilometer = 'km',
+  Mile = 'mi'
+}
+
+export class Athlete {
+  maxHeartRate: number = 0
+  restingHeartRate: number = 0
+  thresholdHeartRate: number = 0
+  heartRateZoneMethod: HeartRateZoneMethod | '' = ''
+  heartRateZones: number[] = [] // only for HeartRateZoneMethod.Custom, zones' high boundaries in bpm
+  functionalThresholdPower: number = 0
+  criticalPower: number = 0 // in watts
+  wPrime: number = 0 // in joules
+  gender: Gender | '' = ''
+  weight: number = 0 // in kg, used by calorie estimation
+  age: number = 0 // in years, used by calorie estimation
+
+  constructor(data?: Athlete) {
+    this.maxHeartRate = data?.maxHeartRate ?? 0
+    this.restingHeartRate = data?.restingHeartRate ?? 0
+    this.thresholdHeartRate = data?.thresholdHeartRate ?? 0
+    this.heartRateZoneMethod = data?.heartRateZoneMethod ?? ''
+    this.heartRateZones = data?.heartRateZones ?? []
+    this.functionalThresholdPower = data?.functionalThresholdPower ?? 0
+    this.criticalPower = data?.criticalPower ?? 0
+    this.wPrime = data?.wPrime ?? 0
+    this.gender = data?.gender ?? ''
+    this.weight = data?.weight ?? 0
+    this.age = data?.age ?? 0
   }
 }
 
+export enum Gender {
+  Male = 'male',
+  Female = 'female'
+}
+
+export enum HeartRateZoneMethod {
+  Custom = 'custom',
+  MaxHeartRate = 'maxHeartRate',
+  ThresholdHeartRate = 'thresholdHeartRate',
+  HeartRateReserve = 'heartRateReserve'
+}
+
+export enum ElevationGain {
+  Smoothed = 'smoothed',
+  Raw = 'raw',
+  Hysteresis = 'hysteresis'
+}
+
+export enum PreprocessStage {
+  RemoveGPSOutliers = 'removeGPSOutliers',
+  CorrectElevation = 'correctElevation',
+  SmoothingElevation = 'smoothingElevation',
+  CalculateGrade = 'calculateGrade'
+}
+
 export enum ToolMode {
   Unknown = 0,
   Edit,
   Combine,
-  SplitPerSession
+  SplitPerSession,
+  RemovePauses
+}
+
+export enum PauseRemovalMethod {
+  Compress = 0,
+  TimerEvents
+}
+
+export enum SimplifyMethod {
+  None = 0,
+  DouglasPeucker,
+  Resample
+}
+
+export enum GapFillMethod {
+  None = 0,
+  Blank,
+  Hold
 }
 
 export enum FileType {
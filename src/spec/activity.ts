@@ -48,6 +48,7 @@ export class Session {
   totalDescent: number | null = null
   totalCycles: number | null = null
   totalCalories: number | null = null
+  estimatedCalories: number | null = null // in kcal, estimated when totalCalories is not recorded
   avgSpeed: number | null = null
   maxSpeed: number | null = null
   avgHeartRate: number | null = null
@@ -56,17 +57,33 @@ export class Session {
   maxCadence: number | null = null
   avgPower: number | null = null
   maxPower: number | null = null
+  normalizedPower: number | null = null // calculated for cycling when not recorded by the device
+  intensityFactor: number | null = null // calculated for cycling when not recorded and FTP is specified
+  trainingStressScore: number | null = null // calculated for cycling when not recorded and FTP is specified
+  variabilityIndex: number | null = null // only for cycling
+  totalWork: number | null = null // in joules, calculated for cycling when not recorded by the device
   avgTemperature: number | null = null
   maxTemperature: number | null = null
   avgAltitude: number | null = null
   maxAltitude: number | null = null
   avgPace: number | null = null
   avgElapsedPace: number | null = null
+  avgGradeAdjustedPace: number | null = null
+  avgSwolf: number | null = null
+  poolLength: number | null = null // in meters
+  fixedPositions: number | null = null
+  elevationGainMethod: string | null = null
+  timeInHeartRateZone: TimeInZone | null = null
+  splits: Split[] = []
+  climbs: Climb[] = []
+  stops: Stop[] = []
+  efficiency: Efficiency | null = null
 
   timezone: number = 0
   workoutType: WorkoutType = WorkoutType.Moving
   laps: Lap[] = []
   records: Record[] = []
+  lengths: Length[] = [] // only for pool swimming
 
   // additional info
   timeCreated: string | null = null
@@ -87,6 +104,7 @@ export class Lap {
   totalAscent: number | null = null
   totalDescent: number | null = null
   totalCalories: number | null = null
+  estimatedCalories: number | null = null // in kcal, estimated when totalCalories is not recorded
   avgSpeed: number | null = null
   maxSpeed: number | null = null
   avgHeartRate: number | null = null
@@ -95,14 +113,82 @@ export class Lap {
   maxCadence: number | null = null
   avgPower: number | null = null
   maxPower: number | null = null
+  normalizedPower: number | null = null // calculated for cycling when not recorded by the device
+  variabilityIndex: number | null = null // only for cycling
+  totalWork: number | null = null // in joules, calculated for cycling when not recorded by the device
   avgPace: number | null = null
   avgElapsedPace: number | null = null
+  avgGradeAdjustedPace: number | null = null
+  minWPrimeBalance: number | null = null // in joules
+  timeInHeartRateZone: TimeInZone | null = null
+}
+
+// TimeInZone: zone 0 is below boundaries[0], the last zone is boundaries[boundaries.length-1] and above.
+export class TimeInZone {
+  boundaries: number[] = []
+  times: number[] = [] // in seconds, times.length = boundaries.length + 1
+}
+
+export class Split {
+  startTime: string = ''
+  distance: number = 0 // in meters
+  elapsedTime: number = 0 // in seconds
+  movingTime: number = 0 // in seconds
+  pace: number = 0 // in seconds per km
+  gradeAdjustedPace?: number // in seconds per km
+  ascent: number = 0
+  descent: number = 0
+  avgHeartRate?: number
+  avgPower?: number
+}
+
+export class Efficiency {
+  paHrDecoupling?: number // in percent
+  pwHrDecoupling?: number // in percent
+  efficiencyFactor?: number // NP per heartbeat, or speed (m/min) per heartbeat if there is no power
+  cardiacDrift?: number // in percent
+}
+
+// Length: an idle (not active) length is a rest interval.
+export class Length {
+  startTime: string = ''
+  active: boolean = false
+  swimStroke?: string
+  duration?: number // in seconds
+  distance?: number // in meters, only for active length
+  totalStrokes?: number
+  avgSwimmingCadence?: number // in strokes/min
+  pace?: number // in seconds per 100m
+  swolf?: number
+}
+
+export class Stop {
+  startIndex: number = 0 // index of session's records, the first stationary record
+  endIndex: number = 0 // index of the record where moving again, records.length if never resumed
+  startTime: string = ''
+  duration: number = 0 // in seconds
+  positionLat?: number
+  positionLong?: number
+}
+
+export class Climb {
+  startIndex: number = 0 // index of session's records
+  endIndex: number = 0
+  length: number = 0 // in meters
+  gain: number = 0 // in meters
+  avgGrade: number = 0
+  maxGrade: number = 0
+  duration?: number // in seconds
+  vam?: number // in meters per hour
+  category: string = 'Uncategorized' // Uncategorized, Cat 4, Cat 3, Cat 2, Cat 1 or HC
 }
 
 export class Record {
   timestamp: string | null = null
   positionLat: number | null = null
   positionLong: number | null = null
+  smoothedPositionLat: number | null = null
+  smoothedPositionLong: number | null = null
   distance: number | null = null
   speed: number | null = null
   altitude: number | null = null
@@ -112,11 +198,16 @@ export class Record {
   temperature: number | null = null
   grade: number = 0
   pace: number | null = null
+  gradeAdjustedPace: number | null = null
+  wPrimeBalance: number | null = null // in joules
+  interpolated: boolean = false
 
   constructor(data?: any) {
     const casted = data as Record
     this.positionLat = casted?.positionLat
     this.positionLong = casted?.positionLong
+    this.smoothedPositionLat = casted?.smoothedPositionLat
+    this.smoothedPositionLong = casted?.smoothedPositionLong
     this.altitude = casted?.altitude
     this.cadence = casted?.cadence
     this.distance = casted?.distance
@@ -127,5 +218,8 @@ export class Record {
     this.temperature = casted?.temperature
     this.grade = casted?.grade
     this.pace = casted?.pace
+    this.gradeAdjustedPace = casted?.gradeAdjustedPace
+    this.wPrimeBalance = casted?.wPrimeBalance
+    this.interpolated = casted?.interpolated ?? false
   }
 }
@@ -0,0 +1,132 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// grossEfficiency is the typical ratio of mechanical work to metabolic energy in cycling. Since 1 kcal is 4.184 kJ,
+// the energy expenditure in kcal is almost equal to the work in kJ.
+const grossEfficiency = 0.24
+
+// CalorieProfile is the athlete's body profile used to estimate calories.
+type CalorieProfile struct {
+	Weight float64 // in kg, required for heart rate and MET based estimation.
+	Age    uint8   // in years, required for heart rate based estimation.
+	Female bool
+}
+
+// EstimateCalories estimates the energy expenditure in kcal of the records, using the first method that is
+// available: power-based from the mechanical work of any sport having power (e.g. cycling, running with a power
+// meter or rowing), heart rate based using Keytel et al. (2005) equations and MET of the sport multiplied by
+// weight and moving time. It returns NaN if none is available.
+func EstimateCalories(records []Record, sport typedef.Sport, profile CalorieProfile, sm Summarizer) float64 {
+	if pm, ok := NewPowerMetrics(records); ok && pm.Work > 0 {
+		return pm.Work / 4184 / grossEfficiency
+	}
+
+	if profile.Weight <= 0 {
+		return math.NaN()
+	}
+
+	if profile.Age > 0 {
+		if kcal := heartRateCalories(records, profile); !math.IsNaN(kcal) {
+			return kcal
+		}
+	}
+
+	movingTime := sm.TotalMovingTime(records, sport)
+	if movingTime == basetype.Uint32Invalid {
+		return math.NaN()
+	}
+	return MET(sport) * profile.Weight * float64(movingTime) / 1000 / 3600
+}
+
+// heartRateCalories sums the energy expenditure of every second having heart rate using Keytel et al. (2005)
+// equations without VO2max, it returns NaN if records have no heart rate.
+//
+// ref: https://doi.org/10.1080/02640410470001730089
+func heartRateCalories(records []Record, profile CalorieProfile) float64 {
+	heartRates := secondSeries(records, func(rec *Record) float64 {
+		if rec.HeartRate == basetype.Uint8Invalid {
+			return math.NaN()
+		}
+		return float64(rec.HeartRate)
+	})
+
+	age := float64(profile.Age)
+	var kcal float64
+	var hasHeartRate bool
+	for _, hr := range heartRates {
+		if math.IsNaN(hr) {
+			continue
+		}
+		hasHeartRate = true
+
+		var kjPerMinute float64
+		if profile.Female {
+			kjPerMinute = -20.4022 + 0.4472*hr - 0.1263*profile.Weight + 0.074*age
+		} else {
+			kjPerMinute = -55.0969 + 0.6309*hr + 0.1988*profile.Weight + 0.2017*age
+		}
+		kcal += math.Max(0, kjPerMinute) / 4.184 / 60
+	}
+	if !hasHeartRate {
+		return math.NaN()
+	}
+
+	return kcal
+}
+
+// MET returns the typical metabolic equivalent of task of the sport from the Compendium of Physical Activities,
+// 1 MET is 1 kcal per kg of body weight per hour.
+func MET(sport typedef.Sport) float64 {
+	switch sport {
+	case typedef.SportRunning:
+		return 9.8
+	case typedef.SportCycling:
+		return 7.5
+	case typedef.SportEBiking:
+		return 4.0
+	case typedef.SportWalking:
+		return 3.5
+	case typedef.SportHiking, typedef.SportSnowshoeing:
+		return 6.0
+	case typedef.SportMountaineering, typedef.SportRockClimbing:
+		return 7.5
+	case typedef.SportSwimming:
+		return 7.0
+	case typedef.SportRowing, typedef.SportPaddling, typedef.SportKayaking, typedef.SportStandUpPaddleboarding:
+		return 6.0
+	case typedef.SportCrossCountrySkiing:
+		return 9.0
+	case typedef.SportAlpineSkiing, typedef.SportSnowboarding:
+		return 5.3
+	case typedef.SportInlineSkating, typedef.SportIceSkating:
+		return 7.0
+	case typedef.SportFitnessEquipment, typedef.SportTraining:
+		return 5.0
+	case typedef.SportDriving, typedef.SportMotorcycling, typedef.SportBoating, typedef.SportFlying,
+		typedef.SportSnowmobiling:
+		return 2.0
+	default:
+		return 4.0
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/openivity/activity-service/activity"
+)
+
+func TestEstimateCalories(t *testing.T) {
+	sm := activity.NewPreprocessor().Summarizer()
+
+	// 10 minutes of records.
+	offsets := make([]int, 601)
+	for i := range offsets {
+		offsets[i] = i
+	}
+
+	tt := []struct {
+		name     string
+		offsets  []int
+		sport    typedef.Sport
+		fill     func(i int, rec *mesgdef.Record)
+		profile  activity.CalorieProfile
+		expected float64
+	}{
+		{
+			name:     "power of any sport",
+			offsets:  offsets,
+			sport:    typedef.SportRunning,
+			fill:     func(i int, rec *mesgdef.Record) { rec.SetPower(250).SetHeartRate(150) },
+			profile:  activity.CalorieProfile{Weight: 70, Age: 30},
+			expected: 250 * 600 / 4184.0 / 0.24,
+		},
+		{
+			name:     "heart rate of male",
+			offsets:  offsets,
+			sport:    typedef.SportRunning,
+			fill:     func(i int, rec *mesgdef.Record) { rec.SetHeartRate(150).SetSpeedScaled(3) },
+			profile:  activity.CalorieProfile{Weight: 70, Age: 30},
+			expected: (-55.0969 + 0.6309*150 + 0.1988*70 + 0.2017*30) / 4.184 * 10,
+		},
+		{
+			name:     "heart rate of female",
+			offsets:  offsets,
+			sport:    typedef.SportRunning,
+			fill:     func(i int, rec *mesgdef.Record) { rec.SetHeartRate(150).SetSpeedScaled(3) },
+			profile:  activity.CalorieProfile{Weight: 60, Age: 30, Female: true},
+			expected: (-20.4022 + 0.4472*150 - 0.1263*60 + 0.074*30) / 4.184 * 10,
+		},
+		{
+			name:     "MET without age",
+			offsets:  offsets,
+			sport:    typedef.SportRunning,
+			fill:     func(i int, rec *mesgdef.Record) { rec.SetHeartRate(150).SetSpeedScaled(3) },
+			profile:  activity.CalorieProfile{Weight: 70},
+			expected: 9.8 * 70 * 600 / 3600,
+		},
+		{
+			name:     "MET without heart rate",
+			offsets:  offsets,
+			sport:    typedef.SportWalking,
+			fill:     func(i int, rec *mesgdef.Record) { rec.SetSpeedScaled(1.5) },
+			profile:  activity.CalorieProfile{Weight: 70, Age: 30},
+			expected: 3.5 * 70 * 600 / 3600,
+		},
+		{
+			name:     "no weight",
+			offsets:  offsets,
+			sport:    typedef.SportRunning,
+			fill:     func(i int, rec *mesgdef.Record) { rec.SetHeartRate(150).SetSpeedScaled(3) },
+			expected: math.NaN(),
+		},
+		{
+			name:     "no records",
+			sport:    typedef.SportRunning,
+			profile:  activity.CalorieProfile{Weight: 70, Age: 30},
+			expected: math.NaN(),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			records := newRecords(tc.offsets, tc.fill)
+			kcal := activity.EstimateCalories(records, tc.sport, tc.profile, sm)
+			if !equalFloat(math.Round(kcal*100)/100, math.Round(tc.expected*100)/100) {
+				t.Fatalf("expected: %g, got: %g", tc.expected, kcal)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+)
+
+const (
+	climbDropTolerance = 10  // A climb ends once the altitude drops this much (in meters) from its peak.
+	minClimbLength     = 300 // in meters
+	minClimbAvgGrade   = 3   // in percent
+)
+
+// ClimbCategory is a climb category using Strava-like scoring (length in meters * average grade in percent).
+type ClimbCategory byte
+
+const (
+	ClimbCategoryNone ClimbCategory = iota // Uncategorized.
+	ClimbCategory4                         // Score >= 8000.
+	ClimbCategory3                         // Score >= 16000.
+	ClimbCategory2                         // Score >= 32000.
+	ClimbCategory1                         // Score >= 64000.
+	ClimbCategoryHC                        // Score >= 80000, hors catégorie.
+)
+
+func (c ClimbCategory) String() string {
+	switch c {
+	case ClimbCategory4:
+		return "Cat 4"
+	case ClimbCategory3:
+		return "Cat 3"
+	case ClimbCategory2:
+		return "Cat 2"
+	case ClimbCategory1:
+		return "Cat 1"
+	case ClimbCategoryHC:
+		return "HC"
+	default:
+		return "Uncategorized"
+	}
+}
+
+// NewClimbCategory categorizes climb from its length in meters and its average grade in percent.
+func NewClimbCategory(length, avgGrade float64) ClimbCategory {
+	switch score := length * avgGrade; {
+	case score >= 80000:
+		return ClimbCategoryHC
+	case score >= 64000:
+		return ClimbCategory1
+	case score >= 32000:
+		return ClimbCategory2
+	case score >= 16000:
+		return ClimbCategory3
+	case score >= 8000:
+		return ClimbCategory4
+	default:
+		return ClimbCategoryNone
+	}
+}
+
+// Climb is a continuous uphill section of a session.
+type Climb struct {
+	StartIndex int           // Index of the record at the bottom of the climb.
+	EndIndex   int           // Index of the record at the top of the climb.
+	Length     float64       // in meters
+	Gain       float64       // in meters
+	AvgGrade   float64       // in percent
+	MaxGrade   float64       // in percent
+	Duration   time.Duration // Zero if records have no timestamp.
+	VAM        float64       // Velocità Ascensionale Media: gain in meters per hour, NaN if Duration is zero.
+	Category   ClimbCategory
+}
+
+// DetectClimbs detects climbs from records' SmoothedAltitude, Grade and Distance produced by the Preprocessor.
+// A climb starts from the lowest point and ends at the highest point before the altitude drops more than
+// 10 meters, it's only reported if it's at least 300 meters long with at least 3% average grade.
+func DetectClimbs(records []Record) []Climb {
+	var climbs []Climb
+	start, peak := -1, -1
+	for i := range records {
+		rec := &records[i]
+		if rec.Distance == basetype.Uint32Invalid || math.IsNaN(rec.SmoothedAltitude) {
+			continue
+		}
+		if start == -1 {
+			start, peak = i, i
+			continue
+		}
+
+		altitude := rec.SmoothedAltitude
+		switch {
+		case altitude > records[peak].SmoothedAltitude:
+			peak = i
+		case records[peak].SmoothedAltitude-altitude >= climbDropTolerance:
+			if climb, ok := newClimb(records, start, peak); ok {
+				climbs = append(climbs, climb)
+			}
+			start, peak = i, i
+		case altitude < records[start].SmoothedAltitude:
+			start, peak = i, i
+		}
+	}
+
+	if start != -1 {
+		if climb, ok := newClimb(records, start, peak); ok {
+			climbs = append(climbs, climb)
+		}
+	}
+
+	return climbs
+}
+
+// newClimb creates new climb from records[start] to records[end], it returns false if it does not qualify as a climb.
+func newClimb(records []Record, start, end int) (Climb, bool) {
+	bottom, top := &records[start], &records[end]
+
+	length := top.DistanceScaled() - bottom.DistanceScaled()
+	gain := top.SmoothedAltitude - bottom.SmoothedAltitude
+	if length < minClimbLength || gain/length*100 < minClimbAvgGrade {
+		return Climb{}, false
+	}
+
+	climb := Climb{
+		StartIndex: start,
+		EndIndex:   end,
+		Length:     length,
+		Gain:       gain,
+		AvgGrade:   gain / length * 100,
+		VAM:        math.NaN(),
+	}
+	climb.Category = NewClimbCategory(climb.Length, climb.AvgGrade)
+
+	for i := start; i <= end; i++ {
+		if !math.IsNaN(records[i].Grade) && records[i].Grade > climb.MaxGrade {
+			climb.MaxGrade = records[i].Grade
+		}
+	}
+
+	if !bottom.Timestamp.IsZero() && !top.Timestamp.IsZero() {
+		climb.Duration = top.Timestamp.Sub(bottom.Timestamp)
+		if climb.Duration > 0 {
+			climb.VAM = gain / climb.Duration.Hours()
+		}
+	}
+
+	return climb, true
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Climb to b, returning the result.
+func (c *Climb) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '{')
+
+	b = append(b, `"startIndex":`...)
+	b = strconv.AppendInt(b, int64(c.StartIndex), 10)
+	b = append(b, ',')
+
+	b = append(b, `"endIndex":`...)
+	b = strconv.AppendInt(b, int64(c.EndIndex), 10)
+	b = append(b, ',')
+
+	b = append(b, `"length":`...)
+	b = strconv.AppendFloat(b, c.Length, 'f', 1, 64)
+	b = append(b, ',')
+
+	b = append(b, `"gain":`...)
+	b = strconv.AppendFloat(b, c.Gain, 'f', 1, 64)
+	b = append(b, ',')
+
+	b = append(b, `"avgGrade":`...)
+	b = strconv.AppendFloat(b, c.AvgGrade, 'f', 1, 64)
+	b = append(b, ',')
+
+	b = append(b, `"maxGrade":`...)
+	b = strconv.AppendFloat(b, c.MaxGrade, 'f', 1, 64)
+	b = append(b, ',')
+
+	if c.Duration != 0 {
+		b = append(b, `"duration":`...)
+		b = strconv.AppendFloat(b, c.Duration.Seconds(), 'g', -1, 64)
+		b = append(b, ',')
+	}
+
+	if !math.IsNaN(c.VAM) {
+		b = append(b, `"vam":`...)
+		b = strconv.AppendFloat(b, c.VAM, 'f', 0, 64)
+		b = append(b, ',')
+	}
+
+	b = append(b, `"category":`...)
+	b = strconv.AppendQuote(b, c.Category.String())
+
+	return append(b, '}')
+}
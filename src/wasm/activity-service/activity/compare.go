@@ -0,0 +1,337 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/openivity/activity-service/geomath"
+)
+
+const (
+	DefaultCompareInterval = 100.0 // Default distance in meters between comparison samples.
+	MinCompareInterval     = 1.0   // Smaller interval is clamped to this.
+	MaxCompareSamples      = 10000 // Interval is widened so the comparison has at most this number of samples.
+)
+
+// Alignment is how attempts are aligned to the reference attempt.
+type Alignment byte
+
+const (
+	// AlignByDistance aligns attempts by their own recorded distance from the start.
+	AlignByDistance Alignment = iota
+	// AlignByPosition aligns attempts by projecting each record onto the nearest point of the reference track,
+	// so distance errors of different devices do not accumulate into the time gaps.
+	AlignByPosition
+)
+
+const (
+	alignSearchWindow = 300  // Number of reference records ahead of the last match searched for the nearest point.
+	alignMaxOffTrack  = 50.0 // Records further than this distance in meters from the reference track are skipped.
+)
+
+// Comparison is the comparison of attempts over the same route sampled at every interval of the reference's distance.
+// The first attempt is the reference, so its gaps and diffs are always zero.
+type Comparison struct {
+	Distances []float64 // Sample distances in meters from the start.
+	Attempts  []AttemptComparison
+}
+
+// AttemptComparison is an attempt's values at each of Comparison's distances, NaN if not available.
+type AttemptComparison struct {
+	ElapsedTimes   []float64 // Seconds since the start to reach the distance.
+	TimeGaps       []float64 // Seconds behind the reference at the distance, negative means ahead.
+	Speeds         []float64 // Average speed in m/s over the preceding interval.
+	SpeedDiffs     []float64 // Speed minus the reference's speed.
+	HeartRates     []float64 // Heart rate in bpm at the distance.
+	HeartRateDiffs []float64 // Heart rate minus the reference's heart rate.
+	DistanceGaps   []float64 // Virtual partner: meters ahead of the reference at the time the reference reaches the distance.
+}
+
+// Compare aligns the attempts' records with the first attempt's records (the reference) and samples them
+// at every interval meters of the reference's distance. An interval <= 0 means DefaultCompareInterval, a positive
+// interval is clamped to MinCompareInterval and widened if needed so there are at most MaxCompareSamples samples.
+func Compare(attempts [][]Record, alignment Alignment, interval float64) Comparison {
+	if len(attempts) == 0 {
+		return Comparison{}
+	}
+	if interval <= 0 {
+		interval = DefaultCompareInterval
+	}
+	interval = max(interval, MinCompareInterval)
+
+	profiles := make([]distanceProfile, len(attempts))
+	profiles[0] = newDistanceProfile(attempts[0])
+	for i := 1; i < len(attempts); i++ {
+		if alignment == AlignByPosition {
+			profiles[i] = newPositionProfile(attempts[i], attempts[0], &profiles[0])
+		} else {
+			profiles[i] = newDistanceProfile(attempts[i])
+		}
+	}
+
+	var c Comparison
+	if n := len(profiles[0].distances); n > 0 {
+		total := profiles[0].distances[n-1]
+		interval = max(interval, total/(MaxCompareSamples-1))
+		for i := 0; float64(i)*interval <= total; i++ {
+			c.Distances = append(c.Distances, float64(i)*interval)
+		}
+	}
+
+	c.Attempts = make([]AttemptComparison, len(profiles))
+	for i := range profiles {
+		ac := &c.Attempts[i]
+		ac.ElapsedTimes = make([]float64, len(c.Distances))
+		ac.Speeds = make([]float64, len(c.Distances))
+		ac.HeartRates = make([]float64, len(c.Distances))
+		for j, d := range c.Distances {
+			ac.ElapsedTimes[j] = profiles[i].timeAt(d)
+			ac.HeartRates[j] = profiles[i].heartRateAt(d)
+			ac.Speeds[j] = math.NaN()
+			if j > 0 {
+				if elapsed := ac.ElapsedTimes[j] - ac.ElapsedTimes[j-1]; elapsed > 0 {
+					ac.Speeds[j] = (d - c.Distances[j-1]) / elapsed
+				}
+			}
+		}
+	}
+
+	ref := &c.Attempts[0]
+	for i := range c.Attempts {
+		ac := &c.Attempts[i]
+		ac.TimeGaps = make([]float64, len(c.Distances))
+		ac.SpeedDiffs = make([]float64, len(c.Distances))
+		ac.HeartRateDiffs = make([]float64, len(c.Distances))
+		ac.DistanceGaps = make([]float64, len(c.Distances))
+		for j, d := range c.Distances {
+			ac.TimeGaps[j] = ac.ElapsedTimes[j] - ref.ElapsedTimes[j]
+			ac.SpeedDiffs[j] = ac.Speeds[j] - ref.Speeds[j]
+			ac.HeartRateDiffs[j] = ac.HeartRates[j] - ref.HeartRates[j]
+			ac.DistanceGaps[j] = profiles[i].distanceAt(ref.ElapsedTimes[j]) - d
+		}
+	}
+
+	return c
+}
+
+// distanceProfile is an attempt's non-decreasing distances with their elapsed times and heart rates.
+type distanceProfile struct {
+	distances  []float64 // in meters
+	times      []float64 // in seconds since the first record
+	heartRates []float64 // in bpm, NaN if not available
+}
+
+func (p *distanceProfile) add(distance, time float64, heartRate uint8) {
+	if n := len(p.distances); n > 0 && distance < p.distances[n-1] {
+		distance = p.distances[n-1]
+	}
+	hr := math.NaN()
+	if heartRate != basetype.Uint8Invalid {
+		hr = float64(heartRate)
+	}
+	p.distances = append(p.distances, distance)
+	p.times = append(p.times, time)
+	p.heartRates = append(p.heartRates, hr)
+}
+
+// newDistanceProfile creates distanceProfile from records' distance relative to the first record having distance.
+func newDistanceProfile(records []Record) distanceProfile {
+	var p distanceProfile
+	var first *Record
+	for i := range records {
+		rec := &records[i]
+		if rec.Timestamp.IsZero() || rec.Distance == basetype.Uint32Invalid {
+			continue
+		}
+		if first == nil {
+			first = rec
+		}
+		p.add(rec.DistanceScaled()-first.DistanceScaled(), rec.Timestamp.Sub(first.Timestamp).Seconds(), rec.HeartRate)
+	}
+	return p
+}
+
+// newPositionProfile creates distanceProfile from the reference's distance at the nearest reference record of each
+// record's position. The search only moves forward along the reference so loops and out-and-back routes are aligned
+// with the matching pass. Until the first match and after the track is lost (e.g. a detour or a recording gap),
+// the search covers the rest of the reference to re-acquire the track.
+func newPositionProfile(records, refRecords []Record, ref *distanceProfile) distanceProfile {
+	// Map reference records having position to their index in the reference profile.
+	var refIndexes, refRecIndexes []int
+	profileIndex := -1
+	for i := range refRecords {
+		rec := &refRecords[i]
+		if rec.Timestamp.IsZero() || rec.Distance == basetype.Uint32Invalid {
+			continue
+		}
+		profileIndex++
+		if _, _, ok := rec.positionDegrees(); ok {
+			refIndexes = append(refIndexes, profileIndex)
+			refRecIndexes = append(refRecIndexes, i)
+		}
+	}
+
+	distanceTo := func(j int, lat, long float64) float64 {
+		refLat, refLong, _ := refRecords[refRecIndexes[j]].positionDegrees()
+		return geomath.HaversineDistance(lat, long, refLat, refLong)
+	}
+	// nearestFrom returns the nearest reference within alignSearchWindow from the given reference index.
+	nearestFrom := func(from int, lat, long float64) (best int, nearest float64) {
+		best, nearest = -1, math.Inf(1)
+		for j := from; j < len(refRecIndexes) && j <= from+alignSearchWindow; j++ {
+			if d := distanceTo(j, lat, long); d < nearest {
+				best, nearest = j, d
+			}
+		}
+		return best, nearest
+	}
+
+	var p distanceProfile
+	var first *Record
+	cur, tracking := 0, false
+	for i := range records {
+		rec := &records[i]
+		lat, long, ok := rec.positionDegrees()
+		if rec.Timestamp.IsZero() || !ok {
+			continue
+		}
+
+		best, nearest := -1, math.Inf(1)
+		if tracking {
+			best, nearest = nearestFrom(cur, lat, long)
+		}
+		if best == -1 || nearest > alignMaxOffTrack {
+			// Re-acquire from the first reference within the track ahead, then refine to the nearest one.
+			tracking = false
+			for j := cur; j < len(refRecIndexes); j++ {
+				if distanceTo(j, lat, long) <= alignMaxOffTrack {
+					best, nearest = nearestFrom(j, lat, long)
+					break
+				}
+			}
+		}
+		if best == -1 || nearest > alignMaxOffTrack {
+			continue
+		}
+		cur, tracking = best, true
+
+		if first == nil {
+			first = rec
+		}
+		p.add(ref.distances[refIndexes[best]], rec.Timestamp.Sub(first.Timestamp).Seconds(), rec.HeartRate)
+	}
+	return p
+}
+
+// timeAt returns the interpolated elapsed time to reach the distance, NaN if the distance is never reached.
+func (p *distanceProfile) timeAt(distance float64) float64 {
+	if len(p.distances) == 0 {
+		return math.NaN()
+	}
+	if distance <= p.distances[0] {
+		return p.times[0]
+	}
+	i := sort.SearchFloat64s(p.distances, distance)
+	if i == len(p.distances) {
+		return math.NaN()
+	}
+	return interpolate(p.distances, p.times, i, distance)
+}
+
+// distanceAt returns the interpolated distance at the elapsed time, NaN if the time is beyond the last record.
+func (p *distanceProfile) distanceAt(time float64) float64 {
+	if len(p.times) == 0 || math.IsNaN(time) {
+		return math.NaN()
+	}
+	if time <= p.times[0] {
+		return p.distances[0]
+	}
+	i := sort.SearchFloat64s(p.times, time)
+	if i == len(p.times) {
+		return math.NaN()
+	}
+	return interpolate(p.times, p.distances, i, time)
+}
+
+// heartRateAt returns the heart rate of the first record reaching the distance.
+func (p *distanceProfile) heartRateAt(distance float64) float64 {
+	i := sort.SearchFloat64s(p.distances, distance)
+	if i == len(p.distances) {
+		return math.NaN()
+	}
+	return p.heartRates[i]
+}
+
+// interpolate linearly interpolates ys at x, where xs[i] is the first x that is >= x and i > 0.
+func interpolate(xs, ys []float64, i int, x float64) float64 {
+	if xs[i] == xs[i-1] {
+		return ys[i]
+	}
+	return lerp(ys[i-1], ys[i], (x-xs[i-1])/(xs[i]-xs[i-1]))
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Comparison to b, returning the result.
+// Values are serialized as arrays matching Distances and NaN values are serialized as null.
+func (c *Comparison) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"distances":`...)
+	b = appendFloatsJSON(b, c.Distances, 1)
+	b = append(b, `,"attempts":[`...)
+	for i := range c.Attempts {
+		b = c.Attempts[i].MarshalAppendJSON(b)
+		if i != len(c.Attempts)-1 {
+			b = append(b, ',')
+		}
+	}
+	return append(b, "]}"...)
+}
+
+// MarshalAppendJSON appends the JSON format encoding of AttemptComparison to b, returning the result.
+func (a *AttemptComparison) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"elapsedTimes":`...)
+	b = appendFloatsJSON(b, a.ElapsedTimes, 1)
+	b = append(b, `,"timeGaps":`...)
+	b = appendFloatsJSON(b, a.TimeGaps, 1)
+	b = append(b, `,"speeds":`...)
+	b = appendFloatsJSON(b, a.Speeds, 2)
+	b = append(b, `,"speedDiffs":`...)
+	b = appendFloatsJSON(b, a.SpeedDiffs, 2)
+	b = append(b, `,"heartRates":`...)
+	b = appendFloatsJSON(b, a.HeartRates, 0)
+	b = append(b, `,"heartRateDiffs":`...)
+	b = appendFloatsJSON(b, a.HeartRateDiffs, 0)
+	b = append(b, `,"distanceGaps":`...)
+	b = appendFloatsJSON(b, a.DistanceGaps, 1)
+	return append(b, '}')
+}
+
+func appendFloatsJSON(b []byte, values []float64, prec int) []byte {
+	b = append(b, '[')
+	for i, v := range values {
+		if math.IsNaN(v) {
+			b = append(b, "null"...)
+		} else {
+			b = strconv.AppendFloat(b, v, 'f', prec, 64)
+		}
+		if i != len(values)-1 {
+			b = append(b, ',')
+		}
+	}
+	return append(b, ']')
+}
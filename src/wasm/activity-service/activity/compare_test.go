@@ -0,0 +1,145 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/muktihari/fit/kit/semicircles"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/openivity/activity-service/activity"
+)
+
+// newLinearAttempt creates records every 10 seconds covering the distance in meters at the given speed in m/s.
+func newLinearAttempt(distance, speed float64) []activity.Record {
+	n := int(distance/(speed*10)) + 1
+	offsets := make([]int, n)
+	for i := range offsets {
+		offsets[i] = i * 10
+	}
+	return newRecords(offsets, func(i int, rec *mesgdef.Record) {
+		rec.SetDistanceScaled(float64(i) * speed * 10)
+	})
+}
+
+func TestCompareByDistance(t *testing.T) {
+	ref := newLinearAttempt(1000, 5)     // reaches 1000m at 200s
+	attempt := newLinearAttempt(1000, 4) // reaches 1000m at 250s
+
+	c := activity.Compare([][]activity.Record{ref, attempt}, activity.AlignByDistance, 100)
+
+	if len(c.Distances) != 11 {
+		t.Fatalf("expected len: 11, got: %d", len(c.Distances))
+	}
+
+	tt := []struct {
+		index        int
+		timeGap      float64
+		speedDiff    float64
+		distanceGap  float64
+		elapsedTimes [2]float64
+	}{
+		{index: 0, timeGap: 0, speedDiff: math.NaN(), distanceGap: 0, elapsedTimes: [2]float64{0, 0}},
+		{index: 5, timeGap: 25, speedDiff: -1, distanceGap: -100, elapsedTimes: [2]float64{100, 125}},
+		{index: 10, timeGap: 50, speedDiff: -1, distanceGap: -200, elapsedTimes: [2]float64{200, 250}},
+	}
+
+	for _, tc := range tt {
+		for i := range c.Attempts {
+			if v := c.Attempts[i].ElapsedTimes[tc.index]; !equalFloat(v, tc.elapsedTimes[i]) {
+				t.Fatalf("attempt[%d][%d]: expected elapsed time: %g, got: %g", i, tc.index, tc.elapsedTimes[i], v)
+			}
+			if v := c.Attempts[0].TimeGaps[tc.index]; v != 0 {
+				t.Fatalf("reference[%d]: expected time gap: 0, got: %g", tc.index, v)
+			}
+		}
+		ac := &c.Attempts[1]
+		if !equalFloat(ac.TimeGaps[tc.index], tc.timeGap) {
+			t.Fatalf("[%d]: expected time gap: %g, got: %g", tc.index, tc.timeGap, ac.TimeGaps[tc.index])
+		}
+		if !equalFloat(ac.SpeedDiffs[tc.index], tc.speedDiff) {
+			t.Fatalf("[%d]: expected speed diff: %g, got: %g", tc.index, tc.speedDiff, ac.SpeedDiffs[tc.index])
+		}
+		if !equalFloat(ac.DistanceGaps[tc.index], tc.distanceGap) {
+			t.Fatalf("[%d]: expected distance gap: %g, got: %g", tc.index, tc.distanceGap, ac.DistanceGaps[tc.index])
+		}
+	}
+}
+
+func TestCompareInterval(t *testing.T) {
+	tt := []struct {
+		name     string
+		distance float64
+		interval float64
+		samples  int
+	}{
+		{name: "default", distance: 1000, interval: 0, samples: 11},
+		{name: "clamped to min", distance: 1000, interval: 0.001, samples: 1001},
+		{name: "widened to max samples", distance: 100000, interval: 1, samples: activity.MaxCompareSamples},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ref := newLinearAttempt(tc.distance, tc.distance/10) // 2 records
+			c := activity.Compare([][]activity.Record{ref}, activity.AlignByDistance, tc.interval)
+			if len(c.Distances) != tc.samples {
+				t.Fatalf("expected samples: %d, got: %d", tc.samples, len(c.Distances))
+			}
+		})
+	}
+}
+
+func TestCompareByPosition(t *testing.T) {
+	// Reference along the equator, a record every second and ~1.1m apart, with recorded distance of 1m each.
+	refOffsets := make([]int, 1001)
+	for i := range refOffsets {
+		refOffsets[i] = i
+	}
+	refFill := func(i int, rec *mesgdef.Record) {
+		rec.SetPositionLat(0).
+			SetPositionLong(semicircles.ToSemicircles(float64(i) * 0.00001)).
+			SetDistanceScaled(float64(i))
+	}
+	ref := newRecords(refOffsets, refFill)
+
+	// The attempt starts in the middle of the reference (beyond the search window) and has a recording gap
+	// between 600 and 950, each record's position is the same as the reference's and has no distance.
+	var indexes, offsets []int
+	for i := 500; i <= 600; i++ {
+		indexes, offsets = append(indexes, i), append(offsets, i-500)
+	}
+	for i := 950; i <= 1000; i++ {
+		indexes, offsets = append(indexes, i), append(offsets, i-750)
+	}
+	attempt := newRecords(offsets, func(i int, rec *mesgdef.Record) {
+		rec.SetPositionLat(0).SetPositionLong(semicircles.ToSemicircles(float64(indexes[i]) * 0.00001))
+	})
+
+	c := activity.Compare([][]activity.Record{ref, attempt}, activity.AlignByPosition, 100)
+
+	expected := map[int]float64{ // sample index: attempt's elapsed time
+		5:  0,   // the start of the attempt
+		6:  100, // before the gap
+		7:  100 + 100.0/350*100,
+		10: 250, // after the gap
+	}
+	for index, elapsed := range expected {
+		if v := c.Attempts[1].ElapsedTimes[index]; !equalFloat(v, elapsed) {
+			t.Fatalf("[%d]: expected elapsed time: %g, got: %g", index, elapsed, v)
+		}
+	}
+}
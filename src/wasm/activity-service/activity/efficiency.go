@@ -0,0 +1,191 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// minEfficiencyDuration is the minimum duration in seconds of paired data to calculate efficiency metrics,
+// halves shorter than 10 minutes are too noisy to be compared.
+const minEfficiencyDuration = 20 * 60
+
+// Efficiency is aerobic efficiency metrics comparing the output (speed or power) to the heart rate.
+// Values are NaN if it can not be calculated.
+type Efficiency struct {
+	PaHRDecoupling   float64 // Speed-to-heart-rate decoupling between the first and second half in percent.
+	PwHRDecoupling   float64 // Power-to-heart-rate decoupling between the first and second half in percent.
+	EfficiencyFactor float64 // NP per heartbeat if power exists, otherwise speed (m/min) per heartbeat.
+	CardiacDrift     float64 // Increase of average heart rate in the second half compared to the first half in percent.
+}
+
+// NewEfficiency calculates efficiency metrics from records, only moving samples are used for speed.
+// It returns false if none of the metrics can be calculated, e.g. records have no heart rate or neither speed nor power.
+func NewEfficiency(records []Record, sport typedef.Sport, sm Summarizer) (Efficiency, bool) {
+	heartRates := secondSeries(records, func(rec *Record) float64 {
+		if rec.HeartRate == basetype.Uint8Invalid {
+			return math.NaN()
+		}
+		return float64(rec.HeartRate)
+	})
+	powers := secondSeries(records, func(rec *Record) float64 {
+		if rec.Power == basetype.Uint16Invalid {
+			return math.NaN()
+		}
+		return float64(rec.Power)
+	})
+	speeds := secondSeries(records, func(rec *Record) float64 {
+		speed := rec.SpeedScaled()
+		if math.IsNaN(speed) {
+			speed = rec.EnhancedSpeedScaled()
+		}
+		if !sm.IsConsideredMoving(sport, speed) {
+			return math.NaN()
+		}
+		return speed
+	})
+
+	efficiency := Efficiency{
+		PaHRDecoupling:   decoupling(speeds, heartRates),
+		PwHRDecoupling:   decoupling(powers, heartRates),
+		EfficiencyFactor: math.NaN(),
+		CardiacDrift:     math.NaN(),
+	}
+
+	// Cardiac drift is only meaningful when the output is also recorded, so we can tell the effort is steady.
+	if !math.IsNaN(efficiency.PaHRDecoupling) || !math.IsNaN(efficiency.PwHRDecoupling) {
+		if first, second, ok := halves(heartRates, heartRates); ok {
+			efficiency.CardiacDrift = (second.input - first.input) / first.input * 100
+		}
+	}
+
+	if power, heartRate, n := pairedMean(powers, heartRates); n >= minEfficiencyDuration {
+		filtered := make([]float64, 0, len(powers))
+		for i := range powers {
+			if !math.IsNaN(powers[i]) {
+				filtered = append(filtered, powers[i])
+			}
+		}
+		if np := normalizedPower(filtered); !math.IsNaN(np) {
+			power = np
+		}
+		efficiency.EfficiencyFactor = power / heartRate
+	} else if speed, heartRate, n := pairedMean(speeds, heartRates); n >= minEfficiencyDuration {
+		efficiency.EfficiencyFactor = speed * 60 / heartRate
+	}
+
+	if math.IsNaN(efficiency.PaHRDecoupling) && math.IsNaN(efficiency.PwHRDecoupling) &&
+		math.IsNaN(efficiency.EfficiencyFactor) && math.IsNaN(efficiency.CardiacDrift) {
+		return Efficiency{}, false
+	}
+
+	return efficiency, true
+}
+
+// halfMean is the mean of output and input within a half.
+type halfMean struct{ output, input float64 }
+
+// halves splits paired samples (both output and input are not NaN) into 2 halves by its count
+// and returns the mean of each half.
+func halves(outputs, inputs []float64) (first, second halfMean, ok bool) {
+	var n int
+	for i := range outputs {
+		if !math.IsNaN(outputs[i]) && !math.IsNaN(inputs[i]) {
+			n++
+		}
+	}
+	if n < minEfficiencyDuration {
+		return first, second, false
+	}
+
+	var count int
+	for i := range outputs {
+		if math.IsNaN(outputs[i]) || math.IsNaN(inputs[i]) {
+			continue
+		}
+		h := &first
+		if count >= n/2 {
+			h = &second
+		}
+		h.output += outputs[i]
+		h.input += inputs[i]
+		count++
+	}
+	first.output, first.input = first.output/float64(n/2), first.input/float64(n/2)
+	second.output, second.input = second.output/float64(n-n/2), second.input/float64(n-n/2)
+
+	return first, second, first.input != 0 && second.input != 0
+}
+
+// decoupling returns the decline of output-to-input ratio from the first half to the second half in percent.
+func decoupling(outputs, inputs []float64) float64 {
+	first, second, ok := halves(outputs, inputs)
+	if !ok || first.output == 0 {
+		return math.NaN()
+	}
+	firstRatio := first.output / first.input
+	secondRatio := second.output / second.input
+	return (firstRatio - secondRatio) / firstRatio * 100
+}
+
+// pairedMean returns the mean of output and input of paired samples and the number of the samples.
+func pairedMean(outputs, inputs []float64) (output, input float64, n int) {
+	for i := range outputs {
+		if math.IsNaN(outputs[i]) || math.IsNaN(inputs[i]) {
+			continue
+		}
+		output += outputs[i]
+		input += inputs[i]
+		n++
+	}
+	if n == 0 {
+		return math.NaN(), math.NaN(), 0
+	}
+	return output / float64(n), input / float64(n), n
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Efficiency to b, returning the result.
+func (e *Efficiency) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '{')
+	if !math.IsNaN(e.PaHRDecoupling) {
+		b = append(b, `"paHrDecoupling":`...)
+		b = strconv.AppendFloat(b, e.PaHRDecoupling, 'f', 2, 64)
+		b = append(b, ',')
+	}
+	if !math.IsNaN(e.PwHRDecoupling) {
+		b = append(b, `"pwHrDecoupling":`...)
+		b = strconv.AppendFloat(b, e.PwHRDecoupling, 'f', 2, 64)
+		b = append(b, ',')
+	}
+	if !math.IsNaN(e.EfficiencyFactor) {
+		b = append(b, `"efficiencyFactor":`...)
+		b = strconv.AppendFloat(b, e.EfficiencyFactor, 'f', 3, 64)
+		b = append(b, ',')
+	}
+	if !math.IsNaN(e.CardiacDrift) {
+		b = append(b, `"cardiacDrift":`...)
+		b = strconv.AppendFloat(b, e.CardiacDrift, 'f', 2, 64)
+		b = append(b, ',')
+	}
+	if b[len(b)-1] == ',' {
+		b = b[:len(b)-1]
+	}
+	return append(b, '}')
+}
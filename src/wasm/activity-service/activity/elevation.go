@@ -0,0 +1,114 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+
+	"github.com/muktihari/fit/profile/basetype"
+)
+
+// ElevationGainMethodDevice is the method name reported when the total ascent and descent are recorded by the device.
+const ElevationGainMethodDevice = "device"
+
+// DefaultElevationGainThreshold is the default threshold in meters used by ElevationGainHysteresis.
+const DefaultElevationGainThreshold = 3
+
+// ElevationGain is an algorithm to calculate total ascent and descent from records.
+type ElevationGain interface {
+	// Name returns the name of the algorithm, it's reported in the session so users know how the ascent is calculated.
+	Name() string
+	// AscentAndDescent returns the total ascent and descent in meters, ok is false if records have no altitude.
+	AscentAndDescent(records []Record) (ascent, descent float64, ok bool)
+}
+
+// ElevationGainSmoothed sums every positive and negative delta of the altitude smoothed by the preprocessor
+// (distance-window smoothing or Kalman filter, depending on the preprocessor's options).
+type ElevationGainSmoothed struct{}
+
+var _ ElevationGain = ElevationGainSmoothed{}
+
+func (ElevationGainSmoothed) Name() string { return "smoothed" }
+
+func (ElevationGainSmoothed) AscentAndDescent(records []Record) (ascent, descent float64, ok bool) {
+	return sumDeltas(records, func(rec *Record) float64 { return rec.SmoothedAltitude }, 0)
+}
+
+// ElevationGainRaw sums every positive and negative delta of the recorded altitude without any smoothing.
+type ElevationGainRaw struct{}
+
+var _ ElevationGain = ElevationGainRaw{}
+
+func (ElevationGainRaw) Name() string { return "raw" }
+
+func (ElevationGainRaw) AscentAndDescent(records []Record) (ascent, descent float64, ok bool) {
+	return sumDeltas(records, rawAltitude, 0)
+}
+
+// ElevationGainHysteresis only counts the altitude change once it exceeds the threshold from the last counted altitude,
+// similar to what Garmin and Strava do to filter out barometric and GPS noise. Zero Threshold uses DefaultElevationGainThreshold.
+type ElevationGainHysteresis struct {
+	Threshold float64 // in meters
+}
+
+var _ ElevationGain = ElevationGainHysteresis{}
+
+func (ElevationGainHysteresis) Name() string { return "hysteresis" }
+
+func (e ElevationGainHysteresis) AscentAndDescent(records []Record) (ascent, descent float64, ok bool) {
+	threshold := e.Threshold
+	if threshold <= 0 {
+		threshold = DefaultElevationGainThreshold
+	}
+	return sumDeltas(records, rawAltitude, threshold)
+}
+
+// rawAltitude returns record's altitude, prefers enhanced altitude if any.
+func rawAltitude(rec *Record) float64 {
+	if rec.EnhancedAltitude != basetype.Uint32Invalid {
+		return rec.EnhancedAltitudeScaled()
+	}
+	return rec.AltitudeScaled()
+}
+
+// sumDeltas sums positive and negative delta between the last counted altitude and the next altitude,
+// a delta is only counted when its absolute value is at least the given threshold.
+func sumDeltas(records []Record, altitude func(rec *Record) float64, threshold float64) (ascent, descent float64, ok bool) {
+	last := math.NaN()
+	for i := range records {
+		cur := altitude(&records[i])
+		if math.IsNaN(cur) {
+			continue
+		}
+		if math.IsNaN(last) {
+			last, ok = cur, true
+			continue
+		}
+
+		delta := cur - last
+		switch {
+		case delta > 0 && delta >= threshold:
+			ascent += delta
+			last = cur
+		case delta < 0 && -delta >= threshold:
+			descent -= delta
+			last = cur
+		case threshold == 0:
+			last = cur
+		}
+	}
+	return ascent, descent, ok
+}
@@ -90,8 +90,8 @@ func (s *DecodeEncoder) Decode(ctx context.Context, r io.Reader) ([]activity.Act
 		}
 
 		wa := lis.File().(*wrapActivity)
-		if len(wa.activity.Records) == 0 {
-			continue
+		if len(wa.activity.Records) == 0 && (len(wa.activity.Lengths) == 0 || len(wa.activity.Sessions) == 0) {
+			continue // Pool swimming may have no records, but it should have lengths and sessions.
 		}
 
 		activities = append(activities, s.convertToActivity(wa.activity))
@@ -107,11 +107,15 @@ func (s *DecodeEncoder) Decode(ctx context.Context, r io.Reader) ([]activity.Act
 		for j := range act.Sessions {
 			ses := &act.Sessions[j]
 
+			ses.FixedPositions = s.preprocessor.RemoveGPSOutliers(ses.Sport, ses.Records)
+			s.preprocessor.SmoothingPosition(ses.Records)
+			s.preprocessor.CorrectElevation(ses.Records)
 			s.preprocessor.CalculateDistanceAndSpeed(ses.Records)
 			s.preprocessor.SmoothingElevation(ses.Records)
 			s.preprocessor.CalculateGrade(ses.Records)
 			if activity.HasPace(ses.Sport) {
 				s.preprocessor.CalculatePace(ses.Sport, ses.Records)
+				s.preprocessor.CalculateGradeAdjustedPace(ses.Records)
 			}
 
 			s.recalculateSummary(ses)
@@ -152,6 +156,11 @@ func (s *DecodeEncoder) convertToActivity(activityFile *filedef.Activity) activi
 		laps[i] = activity.CreateLap(activityFile.Laps[i])
 	}
 
+	lengths := make([]activity.Length, len(activityFile.Lengths))
+	for i := range activityFile.Lengths {
+		lengths[i] = activity.CreateLength(activityFile.Lengths[i])
+	}
+
 	sessions := make([]activity.Session, len(activityFile.Sessions))
 	for i := range activityFile.Sessions {
 		sessions[i] = activity.CreateSession(activityFile.Sessions[i])
@@ -165,7 +174,7 @@ func (s *DecodeEncoder) convertToActivity(activityFile *filedef.Activity) activi
 	//    ref: https://developer.garmin.com/fit/file-types/activity
 	if len(sessions) == 0 {
 		if len(laps) == 0 {
-			lap := activity.NewLapFromRecords(records, typedef.SportGeneric)
+			lap := activity.NewLapFromRecords(records, typedef.SportGeneric, s.preprocessor.Summarizer())
 			laps = append(laps, lap)
 		}
 
@@ -191,6 +200,7 @@ func (s *DecodeEncoder) convertToActivity(activityFile *filedef.Activity) activi
 
 		laps = ses.PutLaps(laps...)
 		records = ses.PutRecords(records...)
+		lengths = ses.PutLengths(lengths...)
 
 		if len(ses.Laps) == 0 {
 			ses.Laps = append(ses.Laps, activity.NewLapFromSession(&sessions[i]))
@@ -219,7 +229,7 @@ func (s *DecodeEncoder) convertToActivity(activityFile *filedef.Activity) activi
 
 	// Handle remaining records that don't belong anywhere.
 	if len(records) != 0 {
-		lap := activity.NewLapFromRecords(records, typedef.SportGeneric)
+		lap := activity.NewLapFromRecords(records, typedef.SportGeneric, s.preprocessor.Summarizer())
 		laps = []activity.Lap{lap}
 		ses := activity.NewSessionFromLaps(laps)
 		ses.Laps = laps
@@ -232,6 +242,20 @@ func (s *DecodeEncoder) convertToActivity(activityFile *filedef.Activity) activi
 
 // recalculateSummary recalculates values based on Laps and Records.
 func (s *DecodeEncoder) recalculateSummary(ses *activity.Session) {
+	if ses.TotalAscent != basetype.Uint16Invalid {
+		ses.ElevationGainMethod = activity.ElevationGainMethodDevice
+	}
+	if ses.FixedPositions != 0 { // Device's distance and speed are derived from the GPS outliers, use the fixed records'.
+		ses.TotalDistance = basetype.Uint32Invalid
+		ses.AvgSpeed, ses.MaxSpeed = basetype.Uint16Invalid, basetype.Uint16Invalid
+		ses.EnhancedAvgSpeed, ses.EnhancedMaxSpeed = basetype.Uint32Invalid, basetype.Uint32Invalid
+		for i := range ses.Laps {
+			lap := &ses.Laps[i]
+			lap.TotalDistance = basetype.Uint32Invalid
+			lap.AvgSpeed, lap.MaxSpeed = basetype.Uint16Invalid, basetype.Uint16Invalid
+			lap.EnhancedAvgSpeed, lap.EnhancedMaxSpeed = basetype.Uint32Invalid, basetype.Uint32Invalid
+		}
+	}
 	records := slices.Clone(ses.Records)
 	if len(ses.Laps) == 1 { // Ensure lap's time windows match with session, FIT produces by Strava contains wrong time.
 		ses.Laps[0].StartTime = ses.StartTime
@@ -251,13 +275,13 @@ func (s *DecodeEncoder) recalculateSummary(ses *activity.Session) {
 		if len(records) == 0 {
 			continue
 		}
-		lapFromRecords := activity.NewLapFromRecords(records[:pos], ses.Sport)
+		lapFromRecords := activity.NewLapFromRecords(records[:pos], ses.Sport, s.preprocessor.Summarizer())
 		aggregator.Fill(lap.Lap, lapFromRecords.Lap)
 		records = records[pos:]
 	}
 	sesFromLaps := activity.NewSessionFromLaps(ses.Laps)
 	aggregator.Fill(ses.Session, sesFromLaps.Session)
-	ses.Summarize()
+	ses.Summarize(s.preprocessor.Summarizer())
 }
 
 func (s *DecodeEncoder) Encode(ctx context.Context, activities []activity.Activity) ([][]byte, error) {
@@ -276,8 +300,13 @@ func (s *DecodeEncoder) Encode(ctx context.Context, activities []activity.Activi
 
 		wa := wrapActivity{activity: filedef.NewActivity()}
 		wa.activity.FileId = *a.Creator.FileId
+		var lengthIndex uint16
 		for j := range a.Sessions {
 			ses := &a.Sessions[j]
+			lengthIndex = ses.SummarizeLengths(lengthIndex)
+			for k := range ses.Lengths {
+				wa.activity.Lengths = append(wa.activity.Lengths, ses.Lengths[k].Length)
+			}
 			for k := range ses.Laps {
 				wa.activity.Laps = append(wa.activity.Laps, ses.Laps[k].Lap)
 			}
@@ -51,6 +51,8 @@ func (w *wrapActivity) Add(mesg proto.Message) {
 		w.activity.Records = append(w.activity.Records, mesgdef.NewRecord(&mesg))
 	case mesgnum.Lap:
 		w.activity.Laps = append(w.activity.Laps, mesgdef.NewLap(&mesg))
+	case mesgnum.Length:
+		w.activity.Lengths = append(w.activity.Lengths, mesgdef.NewLength(&mesg))
 	case mesgnum.SplitSummary:
 		w.activity.SplitSummaries = append(w.activity.SplitSummaries, mesgdef.NewSplitSummary(&mesg))
 	case mesgnum.Session:
@@ -64,7 +66,7 @@ func (w *wrapActivity) Add(mesg proto.Message) {
 }
 
 func (w *wrapActivity) ToFIT(o *mesgdef.Options) proto.FIT {
-	size := 2 + len(w.activity.Records) + len(w.activity.Laps) + len(w.activity.Sessions) +
+	size := 2 + len(w.activity.Records) + len(w.activity.Laps) + len(w.activity.Lengths) + len(w.activity.Sessions) +
 		len(w.activity.SplitSummaries) + len(w.activity.Sports)
 
 	fit := proto.FIT{Messages: make([]proto.Message, 0, size)}
@@ -79,14 +81,18 @@ func (w *wrapActivity) ToFIT(o *mesgdef.Options) proto.FIT {
 	// Create Sports from Sessions, add additional timestamp of the first record timestamp
 	// so Sports will be put before Records, this field will be removed after sorting.
 	w.activity.Sports = make([]*mesgdef.Sport, 0, len(w.activity.Sessions))
-	var firstRecordTimestampField proto.Field
+	firstTimestamp := basetype.Uint32Invalid
 	for i := range w.activity.Records {
 		if timestamp := datetime.ToUint32(w.activity.Records[i].Timestamp); timestamp != basetype.Uint32Invalid {
-			firstRecordTimestampField = factory.CreateField(mesgnum.Record, fieldnum.RecordTimestamp).WithValue(timestamp)
-			firstRecordTimestampField.IsExpandedField = true // Mark for removal after sorting.
+			firstTimestamp = timestamp
 			break
 		}
 	}
+	if firstTimestamp == basetype.Uint32Invalid && len(w.activity.Sessions) > 0 {
+		firstTimestamp = datetime.ToUint32(w.activity.Sessions[0].StartTime) // e.g. pool swimming without records.
+	}
+	firstRecordTimestampField := factory.CreateField(mesgnum.Record, fieldnum.RecordTimestamp).WithValue(firstTimestamp)
+	firstRecordTimestampField.IsExpandedField = true // Mark for removal after sorting.
 
 	for _, ses := range w.activity.Sessions {
 		var ok bool
@@ -110,6 +116,10 @@ func (w *wrapActivity) ToFIT(o *mesgdef.Options) proto.FIT {
 		fit.Messages = append(fit.Messages, w.activity.Records[i].ToMesg(o))
 	}
 
+	for i := range w.activity.Lengths {
+		fit.Messages = append(fit.Messages, w.activity.Lengths[i].ToMesg(o))
+	}
+
 	for i := range w.activity.Laps {
 		fit.Messages = append(fit.Messages, w.activity.Laps[i].ToMesg(o))
 	}
@@ -0,0 +1,160 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/kit/semicircles"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/openivity/activity-service/geomath"
+)
+
+// DefaultFillGapsThreshold is the default minimum gap to be filled, longer than typical smart recording intervals (1-8s)
+// so only the gaps where the device drops out are filled.
+const DefaultFillGapsThreshold = 10 * time.Second
+
+// FillGaps inserts interpolated records into the gaps longer than threshold where records are missing
+// (e.g. tunnels, sensor disconnected) so the records have the given interval. Positions are interpolated along the great circle, while distance and
+// altitude are interpolated linearly. Sensor values (heart rate, cadence, power and temperature) are held from
+// the record before the gap if holdSensors is true, otherwise, those values are left blank.
+// Gaps overlapping any of the given pauses (e.g. timer was stopped by the user) are not filled.
+// Inserted records are marked as Interpolated.
+func FillGaps(records []Record, interval, threshold time.Duration, holdSensors bool, pauses []Pause) []Record {
+	if interval <= 0 || len(records) < 2 {
+		return records
+	}
+	threshold = max(threshold, interval)
+
+	filled := make([]Record, 0, len(records))
+	prev := -1
+	for i := range records {
+		rec := &records[i]
+		if rec.Timestamp.IsZero() {
+			filled = append(filled, *rec)
+			continue
+		}
+
+		if prev != -1 {
+			gap := rec.Timestamp.Sub(records[prev].Timestamp)
+			if gap > threshold && PausedDuration(pauses, records[prev].Timestamp, rec.Timestamp) == 0 {
+				filled = append(filled, interpolateRecords(&records[prev], rec, interval, holdSensors)...)
+			}
+		}
+
+		filled = append(filled, *rec)
+		prev = i
+	}
+
+	return filled
+}
+
+// FilledGap is a gap filled with interpolated records.
+type FilledGap struct {
+	StartTime time.Time // Timestamp of the first interpolated record.
+	EndTime   time.Time // Timestamp of the last interpolated record.
+	Records   int       // Number of interpolated records.
+}
+
+// FilledGaps returns the runs of records marked as Interpolated.
+func FilledGaps(records []Record) []FilledGap {
+	var gaps []FilledGap
+	var cur *FilledGap
+	for i := range records {
+		rec := &records[i]
+		if !rec.Interpolated {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			gaps = append(gaps, FilledGap{StartTime: rec.Timestamp})
+			cur = &gaps[len(gaps)-1]
+		}
+		cur.EndTime = rec.Timestamp
+		cur.Records++
+	}
+	return gaps
+}
+
+// MarshalAppendJSON appends the JSON format encoding of FilledGap to b, returning the result.
+func (g *FilledGap) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"startTime":`...)
+	b = strconv.AppendQuote(b, g.StartTime.Format(time.RFC3339))
+	b = append(b, `,"endTime":`...)
+	b = strconv.AppendQuote(b, g.EndTime.Format(time.RFC3339))
+	b = append(b, `,"records":`...)
+	b = strconv.AppendInt(b, int64(g.Records), 10)
+	return append(b, '}')
+}
+
+// interpolateRecords creates records between a and b (exclusive) every interval.
+func interpolateRecords(a, b *Record, interval time.Duration, holdSensors bool) []Record {
+	gap := b.Timestamp.Sub(a.Timestamp)
+	n := int((gap - 1) / interval) // number of missing records
+
+	var speed = basetype.Uint16Invalid
+	if a.Distance != basetype.Uint32Invalid && b.Distance != basetype.Uint32Invalid && b.Distance >= a.Distance {
+		speed = uint16((b.DistanceScaled() - a.DistanceScaled()) / gap.Seconds() * 1000)
+	}
+
+	records := make([]Record, 0, n)
+	for k := 1; k <= n; k++ {
+		fraction := float64(time.Duration(k)*interval) / float64(gap)
+
+		mesg := mesgdef.NewRecord(nil).SetTimestamp(a.Timestamp.Add(time.Duration(k) * interval))
+
+		if a.PositionLat != basetype.Sint32Invalid && a.PositionLong != basetype.Sint32Invalid &&
+			b.PositionLat != basetype.Sint32Invalid && b.PositionLong != basetype.Sint32Invalid {
+			lat, lon := geomath.IntermediatePoint(
+				a.PositionLatDegrees(), a.PositionLongDegrees(),
+				b.PositionLatDegrees(), b.PositionLongDegrees(),
+				fraction,
+			)
+			mesg.PositionLat = semicircles.ToSemicircles(lat)
+			mesg.PositionLong = semicircles.ToSemicircles(lon)
+		}
+		if a.Distance != basetype.Uint32Invalid && b.Distance != basetype.Uint32Invalid {
+			mesg.SetDistanceScaled(lerp(a.DistanceScaled(), b.DistanceScaled(), fraction))
+		}
+		if a.Altitude != basetype.Uint16Invalid && b.Altitude != basetype.Uint16Invalid {
+			mesg.SetAltitudeScaled(lerp(a.AltitudeScaled(), b.AltitudeScaled(), fraction))
+		}
+		if a.EnhancedAltitude != basetype.Uint32Invalid && b.EnhancedAltitude != basetype.Uint32Invalid {
+			mesg.SetEnhancedAltitudeScaled(lerp(a.EnhancedAltitudeScaled(), b.EnhancedAltitudeScaled(), fraction))
+		}
+		mesg.Speed = speed
+
+		if holdSensors {
+			mesg.HeartRate = a.HeartRate
+			mesg.Cadence = a.Cadence
+			mesg.Power = a.Power
+			mesg.Temperature = a.Temperature
+		}
+
+		rec := CreateRecord(mesg)
+		rec.Interpolated = true
+		records = append(records, rec)
+	}
+
+	return records
+}
+
+// lerp returns linear interpolation between a and b at given fraction.
+func lerp(a, b, fraction float64) float64 {
+	return a + (b-a)*fraction
+}
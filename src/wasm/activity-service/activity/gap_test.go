@@ -0,0 +1,117 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/openivity/activity-service/activity"
+)
+
+func TestFillGaps(t *testing.T) {
+	tt := []struct {
+		name        string
+		offsets     []int
+		threshold   time.Duration
+		holdSensors bool
+		pauses      []activity.Pause
+		expected    []activity.FilledGap
+	}{
+		{
+			name:      "gap longer than threshold is filled",
+			offsets:   []int{0, 1, 15, 16},
+			threshold: 10 * time.Second,
+			expected:  []activity.FilledGap{{StartTime: t0.Add(2 * time.Second), EndTime: t0.Add(14 * time.Second), Records: 13}},
+		},
+		{
+			name:      "gap not longer than threshold is kept",
+			offsets:   []int{0, 1, 11, 12},
+			threshold: 10 * time.Second,
+		},
+		{
+			name:        "sensor values are held",
+			offsets:     []int{0, 12},
+			threshold:   10 * time.Second,
+			holdSensors: true,
+			expected:    []activity.FilledGap{{StartTime: t0.Add(1 * time.Second), EndTime: t0.Add(11 * time.Second), Records: 11}},
+		},
+		{
+			name:      "gap within a timer pause is kept",
+			offsets:   []int{0, 1, 15, 16},
+			threshold: 10 * time.Second,
+			pauses:    []activity.Pause{{StartTime: t0.Add(5 * time.Second), EndTime: t0.Add(10 * time.Second)}},
+		},
+		{
+			name:      "multiple gaps",
+			offsets:   []int{0, 12, 13, 25},
+			threshold: 10 * time.Second,
+			expected: []activity.FilledGap{
+				{StartTime: t0.Add(1 * time.Second), EndTime: t0.Add(11 * time.Second), Records: 11},
+				{StartTime: t0.Add(14 * time.Second), EndTime: t0.Add(24 * time.Second), Records: 11},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			records := newRecords(tc.offsets, func(i int, rec *mesgdef.Record) {
+				rec.SetDistanceScaled(float64(tc.offsets[i]) * 5).SetHeartRate(uint8(120 + i))
+			})
+
+			filled := activity.FillGaps(records, time.Second, tc.threshold, tc.holdSensors, tc.pauses)
+
+			var expectedLen int
+			for _, g := range tc.expected {
+				expectedLen += g.Records
+			}
+			if len(filled) != len(records)+expectedLen {
+				t.Fatalf("expected: %d records, got: %d", len(records)+expectedLen, len(filled))
+			}
+
+			for i := 1; i < len(filled); i++ {
+				if !filled[i].Interpolated {
+					continue
+				}
+				if !filled[i].Timestamp.After(filled[i-1].Timestamp) {
+					t.Fatalf("[%d] expected timestamp after %s, got: %s", i, filled[i-1].Timestamp, filled[i].Timestamp)
+				}
+				if filled[i].Distance < filled[i-1].Distance {
+					t.Fatalf("[%d] expected distance not less than %d, got: %d", i, filled[i-1].Distance, filled[i].Distance)
+				}
+				expectedHR := basetype.Uint8Invalid
+				if tc.holdSensors {
+					expectedHR = filled[i-1].HeartRate
+				}
+				if filled[i].HeartRate != expectedHR {
+					t.Fatalf("[%d] expected heart rate: %d, got: %d", i, expectedHR, filled[i].HeartRate)
+				}
+			}
+
+			gaps := activity.FilledGaps(filled)
+			if len(gaps) != len(tc.expected) {
+				t.Fatalf("expected: %d gaps, got: %d", len(tc.expected), len(gaps))
+			}
+			for i := range gaps {
+				if gaps[i] != tc.expected[i] {
+					t.Fatalf("[%d] expected: %+v, got: %+v", i, tc.expected[i], gaps[i])
+				}
+			}
+		})
+	}
+}
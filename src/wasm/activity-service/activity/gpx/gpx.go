@@ -113,16 +113,29 @@ loop:
 		}
 
 		// Preprocessing...
+		fixedPositions := s.preprocessor.RemoveGPSOutliers(sport, records)
+		s.preprocessor.SmoothingPosition(records)
+		s.preprocessor.CorrectElevation(records)
 		s.preprocessor.CalculateDistanceAndSpeed(records)
 		if activity.HasPace(sport) {
 			s.preprocessor.CalculatePace(sport, records)
 		}
 		s.preprocessor.SmoothingElevation(records)
 		s.preprocessor.CalculateGrade(records)
+		if activity.HasPace(sport) {
+			s.preprocessor.CalculateGradeAdjustedPace(records)
+		}
 
 		// We can only calculate laps' summary after preprocessing.
+		// recordsByLap holds copies of records before preprocessing, point them to the preprocessed records.
+		var cur int
+		for i := range recordsByLap {
+			n := len(recordsByLap[i])
+			recordsByLap[i] = records[cur : cur+n]
+			cur += n
+		}
 		for i := range recordsByLap {
-			lap := activity.NewLapFromRecords(recordsByLap[i], sport)
+			lap := activity.NewLapFromRecords(recordsByLap[i], sport, s.preprocessor.Summarizer())
 			laps = append(laps, lap)
 		}
 
@@ -133,7 +146,8 @@ loop:
 		session := activity.NewSessionFromLaps(laps)
 		session.Records = records
 		session.Laps = laps
-		session.Summarize()
+		session.FixedPositions = fixedPositions
+		session.Summarize(s.preprocessor.Summarizer())
 
 		sessions = append(sessions, session)
 
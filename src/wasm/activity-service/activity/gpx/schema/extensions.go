@@ -170,3 +170,73 @@ func (t *TrackPointExtension) MarshalXML(enc *xml.Encoder, se xml.StartElement)
 
 	return enc.EncodeToken(se.End())
 }
+
+const xmlnsopenivityseg = "https://openivity.github.io/xmlschemas/SegmentExtension/v1"
+
+// SegmentExtension is our GPX route extension to store user-defined segment's settings, so they are kept
+// when the segment library file is shared.
+type SegmentExtension struct {
+	Radius float64 // Radius in meters around segment's points, zero if not specified.
+}
+
+func (s *SegmentExtension) UnmarshalToken(tok *xmltokenizer.Tokenizer, se *xmltokenizer.Token) error {
+	for {
+		token, err := tok.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if token.IsEndElementOf(se) {
+			return nil
+		}
+		if token.IsEndElement {
+			continue
+		}
+
+		switch string(token.Name.Local) {
+		case "radius":
+			val, err := strconv.ParseFloat(string(token.Data), 64)
+			if err != nil {
+				return err
+			}
+			if val > 0 {
+				s.Radius = val
+			}
+		}
+	}
+
+	return nil
+}
+
+var _ xml.Marshaler = (*SegmentExtension)(nil)
+
+func (s *SegmentExtension) MarshalXML(enc *xml.Encoder, se xml.StartElement) (err error) {
+	if s.Radius <= 0 { // omit
+		return nil
+	}
+
+	if err = enc.EncodeToken(se); err != nil {
+		return err
+	}
+
+	ext := xml.StartElement{
+		Name: xml.Name{Local: "seg:SegmentExtension"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns:seg"}, Value: xmlnsopenivityseg}},
+	}
+	if err = enc.EncodeToken(ext); err != nil {
+		return err
+	}
+	if err = xmlutils.EncodeElement(enc,
+		xml.StartElement{Name: xml.Name{Local: "seg:radius"}},
+		xml.CharData(strconv.FormatFloat(s.Radius, 'g', -1, 64))); err != nil {
+		return fmt.Errorf("radius: %w", err)
+	}
+	if err = enc.EncodeToken(ext.End()); err != nil {
+		return err
+	}
+
+	return enc.EncodeToken(se.End())
+}
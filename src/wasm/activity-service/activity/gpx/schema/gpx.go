@@ -50,6 +50,7 @@ type GPX struct {
 	Version string   `xml:"version,attr"`
 
 	Metadata Metadata `xml:"metadata,omitempty"`
+	Routes   []Route  `xml:"rte,omitempty"`
 	Tracks   []Track  `xml:"trk,omitempty"`
 }
 
@@ -88,6 +89,15 @@ func (g *GPX) UnmarshalToken(tok *xmltokenizer.Tokenizer, se *xmltokenizer.Token
 			if err != nil {
 				return fmt.Errorf("metadata: %w", err)
 			}
+		case "rte":
+			var route Route
+			se := xmltokenizer.GetToken().Copy(token)
+			err = route.UnmarshalToken(tok, se)
+			xmltokenizer.PutToken(se)
+			if err != nil {
+				return fmt.Errorf("route: %w", err)
+			}
+			g.Routes = append(g.Routes, route)
 		case "trk":
 			var track Track
 			se := xmltokenizer.GetToken().Copy(token)
@@ -112,6 +122,11 @@ func (g *GPX) Validate() error {
 		return fmt.Errorf("validate metadata: %w", err)
 	}
 
+	for i, route := range g.Routes {
+		if err := route.Validate(); err != nil {
+			return fmt.Errorf("routes[%d]: %w", i, err)
+		}
+	}
 	for i, track := range g.Tracks {
 		if err := track.Validate(); err != nil {
 			return fmt.Errorf("tracks[%d]: %w", i, err)
@@ -150,6 +165,12 @@ func (g *GPX) MarshalXML(enc *xml.Encoder, se xml.StartElement) error {
 		return fmt.Errorf("metadata: %w", err)
 	}
 
+	for i := range g.Routes {
+		if err := g.Routes[i].MarshalXML(enc, xml.StartElement{Name: xml.Name{Local: "rte"}}); err != nil {
+			return fmt.Errorf("rte[%d]: %w", i, err)
+		}
+	}
+
 	for i := range g.Tracks {
 		if err := g.Tracks[i].MarshalXML(enc, xml.StartElement{Name: xml.Name{Local: "trk"}}); err != nil {
 			return fmt.Errorf("trk[%d]: %w", i, err)
@@ -0,0 +1,113 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package schema
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/muktihari/xmltokenizer"
+	"github.com/openivity/activity-service/xmlutils"
+)
+
+// Route is an ordered list of waypoints leading to a destination, we use it to store user-defined segments.
+type Route struct {
+	Name             string           `xml:"name,omitempty"`
+	Routepoints      []Waypoint       `xml:"rtept,omitempty"`
+	SegmentExtension SegmentExtension `xml:"extensions>SegmentExtension,omitempty"`
+}
+
+func (r *Route) UnmarshalToken(tok *xmltokenizer.Tokenizer, se *xmltokenizer.Token) error {
+	for {
+		token, err := tok.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if token.IsEndElementOf(se) {
+			return nil
+		}
+		if token.IsEndElement {
+			continue
+		}
+
+		switch string(token.Name.Local) {
+		case "name":
+			r.Name = html.UnescapeString(string(token.Data)) // Segment names are user input, e.g. "A & B".
+		case "extensions":
+			se := xmltokenizer.GetToken().Copy(token)
+			err = r.SegmentExtension.UnmarshalToken(tok, se)
+			xmltokenizer.PutToken(se)
+			if err != nil {
+				return fmt.Errorf("extensions: %w", err)
+			}
+		case "rtept":
+			var rtept Waypoint
+			se := xmltokenizer.GetToken().Copy(token)
+			err = rtept.UnmarshalToken(tok, se)
+			xmltokenizer.PutToken(se)
+			if err != nil {
+				return fmt.Errorf("rtept: %w", err)
+			}
+			r.Routepoints = append(r.Routepoints, rtept)
+		}
+	}
+
+	return nil
+}
+
+func (r *Route) Validate() error {
+	if r == nil {
+		return nil
+	}
+	for i := range r.Routepoints {
+		if err := r.Routepoints[i].Validate(); err != nil {
+			return fmt.Errorf("routepoints[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+var _ xml.Marshaler = (*Route)(nil)
+
+func (r *Route) MarshalXML(enc *xml.Encoder, se xml.StartElement) error {
+	if err := enc.EncodeToken(se); err != nil {
+		return err
+	}
+
+	if len(r.Name) != 0 {
+		if err := xmlutils.EncodeElement(enc, xmlutils.StartElement("name"), xml.CharData(r.Name)); err != nil {
+			return fmt.Errorf("name: %w", err)
+		}
+	}
+
+	if err := r.SegmentExtension.MarshalXML(enc, xmlutils.StartElement("extensions")); err != nil {
+		return fmt.Errorf("extensions: %w", err)
+	}
+
+	for i := range r.Routepoints {
+		if err := r.Routepoints[i].MarshalXML(enc, xmlutils.StartElement("rtept")); err != nil {
+			return fmt.Errorf("rtept[%d]: %w", i, err)
+		}
+	}
+
+	return enc.EncodeToken(se.End())
+}
@@ -186,6 +186,14 @@ type Waypoint struct {
 	TrackPointExtension TrackPointExtension `xml:"extensions>TrackPointExtension,omitempty"`
 }
 
+// NewWaypoint creates new waypoint of given coordinate (in degrees) without any other data.
+func NewWaypoint(lat, lon float64) Waypoint {
+	var w Waypoint
+	w.reset()
+	w.Lat, w.Lon = lat, lon
+	return w
+}
+
 func (w *Waypoint) reset() {
 	w.Lat = math.NaN()
 	w.Lon = math.NaN()
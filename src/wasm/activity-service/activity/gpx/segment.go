@@ -0,0 +1,149 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package gpx
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/muktihari/xmltokenizer"
+	"github.com/openivity/activity-service/activity"
+	"github.com/openivity/activity-service/activity/gpx/schema"
+	"github.com/openivity/activity-service/mem"
+	"github.com/openivity/activity-service/xmlutils"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	segmentsCreator = "openivity.github.io"
+	segmentsDesc    = "The segments are created by openivity.github.io"
+)
+
+// DecodeSegments decodes user-defined segments from a GPX file, each route or track is a segment.
+// Waypoints without coordinate are skipped, and routes or tracks having less than 2 waypoints are ignored.
+// Segment's radius is read from the route's SegmentExtension, zero if not specified.
+func DecodeSegments(r io.Reader) ([]activity.Segment, error) {
+	tok := xmltokenizer.New(r)
+
+	var gpx schema.GPX
+loop:
+	for {
+		token, err := tok.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch string(token.Name.Local) {
+		case "gpx":
+			se := xmltokenizer.GetToken().Copy(token)
+			err = gpx.UnmarshalToken(tok, se)
+			xmltokenizer.PutToken(se)
+			if err != nil {
+				return nil, err
+			}
+			break loop
+		}
+	}
+
+	segments := make([]activity.Segment, 0, len(gpx.Routes)+len(gpx.Tracks))
+	for i := range gpx.Routes {
+		route := &gpx.Routes[i]
+		segments = appendSegment(segments, route.Name, route.SegmentExtension.Radius, route.Routepoints)
+	}
+	for i := range gpx.Tracks {
+		var trackpoints []schema.Waypoint
+		for j := range gpx.Tracks[i].TrackSegments {
+			trackpoints = append(trackpoints, gpx.Tracks[i].TrackSegments[j].Trackpoints...)
+		}
+		segments = appendSegment(segments, gpx.Tracks[i].Name, 0, trackpoints)
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("gpx: no segment")
+	}
+
+	return segments, nil
+}
+
+func appendSegment(segments []activity.Segment, name string, radius float64, waypoints []schema.Waypoint) []activity.Segment {
+	segment := activity.Segment{
+		Name:   name,
+		Lats:   make([]float64, 0, len(waypoints)),
+		Longs:  make([]float64, 0, len(waypoints)),
+		Radius: radius,
+	}
+	for i := range waypoints {
+		if math.IsNaN(waypoints[i].Lat) || math.IsNaN(waypoints[i].Lon) {
+			continue
+		}
+		segment.Lats = append(segment.Lats, waypoints[i].Lat)
+		segment.Longs = append(segment.Longs, waypoints[i].Lon)
+	}
+	if !segment.IsValid() {
+		return segments
+	}
+	if segment.Name == "" {
+		segment.Name = "Segment " + strconv.Itoa(len(segments)+1)
+	}
+	return append(segments, segment)
+}
+
+// EncodeSegments encodes segments into a GPX file as routes, so a segment library can be shared as a single file.
+// Segment's radius is kept in the route's SegmentExtension.
+func EncodeSegments(segments []activity.Segment) ([]byte, error) {
+	gpx := schema.GPX{
+		Creator: segmentsCreator,
+		Metadata: schema.Metadata{
+			Desc: segmentsDesc,
+			Link: &schema.Link{Href: metadataLink},
+		},
+		Routes: make([]schema.Route, 0, len(segments)),
+	}
+
+	for i := range segments {
+		segment := &segments[i]
+		if !segment.IsValid() {
+			return nil, fmt.Errorf("segment[%d]: should have at least start and end points", i)
+		}
+		route := schema.Route{
+			Name:             segment.Name,
+			Routepoints:      make([]schema.Waypoint, 0, len(segment.Lats)),
+			SegmentExtension: schema.SegmentExtension{Radius: segment.Radius},
+		}
+		for j := range segment.Lats {
+			route.Routepoints = append(route.Routepoints, schema.NewWaypoint(segment.Lats[j], segment.Longs[j]))
+		}
+		gpx.Routes = append(gpx.Routes, route)
+	}
+
+	if err := gpx.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid gpx: %w", err)
+	}
+
+	buf := mem.GetBuffer()
+	defer mem.PutBuffer(buf)
+
+	if err := xmlutils.MarshalWrite(buf, &gpx); err != nil {
+		return nil, fmt.Errorf("could not marshal gpx: %w", err)
+	}
+
+	return slices.Clone(buf.Bytes()), nil
+}
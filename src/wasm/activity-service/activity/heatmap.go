@@ -0,0 +1,138 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/openivity/activity-service/geomath"
+)
+
+// Heatmap zoom levels, a cell is a pixel of a 256x256 map tile at the zoom level, e.g. ~9.5m on the equator at zoom 14.
+// Zoom is capped at ~2.4m cells since GPS is not more accurate than that, and the number of cells (and the result's
+// size) grows fourfold on every zoom level.
+const (
+	DefaultHeatmapZoom = 14
+	MaxHeatmapZoom     = 16
+)
+
+const (
+	heatmapTileSize = 256
+	heatmapMaxJump  = 1000.0 // Consecutive positions further apart than this distance in meters are not connected.
+)
+
+// Heatmap counts how many times tracks pass through each cell of a Web Mercator grid.
+type Heatmap struct {
+	zoom  uint8
+	cells map[uint64]uint32 // Key is x<<32 | y.
+}
+
+// HeatmapCell is a cell of the heatmap, X and Y are the pixel coordinates of the world map at the zoom level,
+// so the cell is in tile (X/256, Y/256).
+type HeatmapCell struct {
+	X, Y  uint32
+	Count uint32 // Number of passes through the cell.
+}
+
+// NewHeatmap creates new heatmap at the zoom level, zoom greater than MaxHeatmapZoom is capped.
+func NewHeatmap(zoom uint8) *Heatmap {
+	return &Heatmap{
+		zoom:  min(zoom, MaxHeatmapZoom),
+		cells: make(map[uint64]uint32),
+	}
+}
+
+// Zoom returns the zoom level of the heatmap.
+func (h *Heatmap) Zoom() uint8 { return h.zoom }
+
+// Len returns the number of cells passed through.
+func (h *Heatmap) Len() int { return len(h.cells) }
+
+// Add adds the track of records' positions to the heatmap. A cell is counted once each time the track enters it,
+// cells between consecutive positions are filled so the track stays continuous when zoomed in.
+func (h *Heatmap) Add(records []Record) {
+	size := float64(uint32(heatmapTileSize) << h.zoom)
+
+	var prevLat, prevLong, prevX, prevY float64
+	last, hasPrev := uint64(math.MaxUint64), false
+	for i := range records {
+		lat, long, ok := records[i].positionDegrees()
+		if !ok {
+			continue
+		}
+
+		x, y := geomath.WebMercator(lat, long)
+		x, y = math.Min(x*size, size-1), math.Min(y*size, size-1)
+
+		steps := 1
+		if hasPrev && geomath.HaversineDistance(prevLat, prevLong, lat, long) <= heatmapMaxJump {
+			steps = max(1, int(math.Ceil(math.Max(math.Abs(x-prevX), math.Abs(y-prevY)))))
+		} else {
+			prevX, prevY = x, y
+		}
+
+		for s := 1; s <= steps; s++ {
+			fraction := float64(s) / float64(steps)
+			key := uint64(lerp(prevX, x, fraction))<<32 | uint64(lerp(prevY, y, fraction))
+			if key != last {
+				h.cells[key]++
+				last = key
+			}
+		}
+
+		prevLat, prevLong, prevX, prevY, hasPrev = lat, long, x, y, true
+	}
+}
+
+// Cells returns the heatmap's cells sorted by X then Y.
+func (h *Heatmap) Cells() []HeatmapCell {
+	cells := make([]HeatmapCell, 0, len(h.cells))
+	for key, count := range h.cells {
+		cells = append(cells, HeatmapCell{X: uint32(key >> 32), Y: uint32(key), Count: count})
+	}
+	slices.SortFunc(cells, func(a, b HeatmapCell) int {
+		if a.X != b.X {
+			return cmp.Compare(a.X, b.X)
+		}
+		return cmp.Compare(a.Y, b.Y)
+	})
+	return cells
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Heatmap to b, returning the result.
+// Cells are serialized as a flat array of x, y and count triples to keep it compact.
+func (h *Heatmap) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"zoom":`...)
+	b = strconv.AppendUint(b, uint64(h.zoom), 10)
+	b = append(b, `,"tileSize":`...)
+	b = strconv.AppendUint(b, heatmapTileSize, 10)
+	b = append(b, `,"cells":[`...)
+	cells := h.Cells()
+	for i := range cells {
+		b = strconv.AppendUint(b, uint64(cells[i].X), 10)
+		b = append(b, ',')
+		b = strconv.AppendUint(b, uint64(cells[i].Y), 10)
+		b = append(b, ',')
+		b = strconv.AppendUint(b, uint64(cells[i].Count), 10)
+		if i != len(cells)-1 {
+			b = append(b, ',')
+		}
+	}
+	return append(b, "]}"...)
+}
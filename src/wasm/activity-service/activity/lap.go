@@ -30,6 +30,12 @@ import (
 // Lap is a workout lap. It wraps FIT SDK's mesgdef.Lap as its base.
 type Lap struct {
 	*mesgdef.Lap
+
+	VariabilityIndex     float64 // NormalizedPower / AvgPower, zero if not calculated.
+	AvgGradeAdjustedPace float64 // in seconds per km, zero if not calculated.
+	MinWPrimeBalance     float64 // Lowest W' balance in joules, NaN if not calculated.
+	EstimatedCalories    float64 // in kcal, estimated when TotalCalories is not recorded, zero if not estimated.
+	TimeInHeartRateZone  TimeInZone
 }
 
 // CreateLap creates new lap.
@@ -37,7 +43,7 @@ func CreateLap(lap *mesgdef.Lap) Lap {
 	if lap == nil {
 		lap = mesgdef.NewLap(nil)
 	}
-	return Lap{Lap: lap}
+	return Lap{Lap: lap, MinWPrimeBalance: math.NaN()}
 }
 
 // EndTime returns lap's EndTime (StartTime + TotalElapsedTime).
@@ -54,7 +60,7 @@ func (l *Lap) EndTime() time.Time {
 }
 
 // CreateLap creates new lap from records.
-func NewLapFromRecords(records []Record, sport typedef.Sport) Lap {
+func NewLapFromRecords(records []Record, sport typedef.Sport, sm Summarizer) Lap {
 	lap := CreateLap(
 		mesgdef.NewLap(nil).
 			SetSport(sport).
@@ -175,8 +181,8 @@ func NewLapFromRecords(records []Record, sport typedef.Sport) Lap {
 	}
 	lap.TotalTimerTime = lap.TotalElapsedTime
 
-	lap.TotalMovingTime = TotalMovingTime(records, lap.Sport)
-	lap.TotalAscent, lap.TotalDescent = TotalAscentAndDescent(records)
+	lap.TotalMovingTime = sm.TotalMovingTime(records, lap.Sport)
+	lap.TotalAscent, lap.TotalDescent = sm.TotalAscentAndDescent(records)
 
 	return lap
 }
@@ -251,6 +257,11 @@ func (l *Lap) MarshalAppendJSON(b []byte) []byte {
 		b = strconv.AppendUint(b, uint64(l.TotalCalories), 10)
 		b = append(b, ',')
 	}
+	if l.EstimatedCalories > 0 {
+		b = append(b, `"estimatedCalories":`...)
+		b = strconv.AppendFloat(b, l.EstimatedCalories, 'f', 0, 64)
+		b = append(b, ',')
+	}
 
 	avgSpeed := l.AvgSpeedScaled()
 	if math.IsNaN(avgSpeed) {
@@ -302,6 +313,26 @@ func (l *Lap) MarshalAppendJSON(b []byte) []byte {
 		b = strconv.AppendUint(b, uint64(l.MaxPower), 10)
 		b = append(b, ',')
 	}
+	if l.NormalizedPower != basetype.Uint16Invalid {
+		b = append(b, `"normalizedPower":`...)
+		b = strconv.AppendUint(b, uint64(l.NormalizedPower), 10)
+		b = append(b, ',')
+	}
+	if l.VariabilityIndex != 0 {
+		b = append(b, `"variabilityIndex":`...)
+		b = strconv.AppendFloat(b, l.VariabilityIndex, 'g', 4, 64)
+		b = append(b, ',')
+	}
+	if l.TotalWork != basetype.Uint32Invalid {
+		b = append(b, `"totalWork":`...)
+		b = strconv.AppendUint(b, uint64(l.TotalWork), 10)
+		b = append(b, ',')
+	}
+	if !math.IsNaN(l.MinWPrimeBalance) {
+		b = append(b, `"minWPrimeBalance":`...)
+		b = strconv.AppendFloat(b, l.MinWPrimeBalance, 'f', 0, 64)
+		b = append(b, ',')
+	}
 	if l.AvgTemperature != basetype.Sint8Invalid {
 		b = append(b, `"avgTemperature":`...)
 		b = strconv.AppendInt(b, int64(l.AvgTemperature), 10)
@@ -347,6 +378,17 @@ func (l *Lap) MarshalAppendJSON(b []byte) []byte {
 			b = append(b, ',')
 		}
 	}
+	if l.AvgGradeAdjustedPace != 0 {
+		b = append(b, `"avgGradeAdjustedPace":`...)
+		b = strconv.AppendFloat(b, l.AvgGradeAdjustedPace, 'g', -1, 64)
+		b = append(b, ',')
+	}
+
+	if l.TimeInHeartRateZone.IsValid() {
+		b = append(b, `"timeInHeartRateZone":`...)
+		b = l.TimeInHeartRateZone.MarshalAppendJSON(b)
+		b = append(b, ',')
+	}
 
 	if b[len(b)-1] == '{' {
 		return b[:len(b)-1]
@@ -0,0 +1,142 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// Length is a pool swimming length, it wraps FIT SDK's mesgdef.Length as its base.
+// An idle length is a rest interval between active lengths.
+type Length struct {
+	*mesgdef.Length
+
+	PoolLength float64 // Pool length in meters taken from the session, NaN if unknown.
+}
+
+// CreateLength creates new length.
+func CreateLength(length *mesgdef.Length) Length {
+	if length == nil {
+		length = mesgdef.NewLength(nil)
+	}
+	return Length{Length: length, PoolLength: math.NaN()}
+}
+
+// IsActive reports whether the length is swum, otherwise it's a rest interval.
+func (l *Length) IsActive() bool { return l.LengthType == typedef.LengthTypeActive }
+
+// Duration returns length's elapsed time in seconds, fallback to timer time. It returns NaN if both are invalid.
+func (l *Length) Duration() float64 {
+	if l.TotalElapsedTime != basetype.Uint32Invalid {
+		return l.TotalElapsedTimeScaled()
+	}
+	return l.TotalTimerTimeScaled()
+}
+
+// Pace returns length's pace in seconds per 100 meters, NaN if the length is idle or the pool length is unknown.
+func (l *Length) Pace() float64 {
+	if !l.IsActive() || math.IsNaN(l.PoolLength) || l.PoolLength == 0 {
+		return math.NaN()
+	}
+	return l.Duration() / l.PoolLength * 100
+}
+
+// SWOLF returns length's swim golf score: duration in seconds + stroke count, lower is more efficient.
+// It returns NaN if the length is idle or has no stroke count.
+func (l *Length) SWOLF() float64 {
+	if !l.IsActive() || l.TotalStrokes == basetype.Uint16Invalid {
+		return math.NaN()
+	}
+	return math.Round(l.Duration()) + float64(l.TotalStrokes)
+}
+
+// AvgSWOLF returns average SWOLF of active lengths, NaN if no length has SWOLF.
+func AvgSWOLF(lengths []Length) float64 {
+	var sum float64
+	var count int
+	for i := range lengths {
+		swolf := lengths[i].SWOLF()
+		if math.IsNaN(swolf) {
+			continue
+		}
+		sum += swolf
+		count++
+	}
+	if count == 0 {
+		return math.NaN()
+	}
+	return sum / float64(count)
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Length to b, returning the result.
+func (l *Length) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '{')
+	if !l.StartTime.IsZero() {
+		b = append(b, `"startTime":`...)
+		b = strconv.AppendQuote(b, l.StartTime.Format(time.RFC3339))
+		b = append(b, ',')
+	}
+	if l.IsActive() {
+		b = append(b, `"active":true,`...)
+	} else {
+		b = append(b, `"active":false,`...)
+	}
+	if l.SwimStroke != typedef.SwimStrokeInvalid {
+		b = append(b, `"swimStroke":`...)
+		b = strconv.AppendQuote(b, l.SwimStroke.String())
+		b = append(b, ',')
+	}
+	if duration := l.Duration(); !math.IsNaN(duration) {
+		b = append(b, `"duration":`...)
+		b = strconv.AppendFloat(b, duration, 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if l.IsActive() && !math.IsNaN(l.PoolLength) {
+		b = append(b, `"distance":`...)
+		b = strconv.AppendFloat(b, l.PoolLength, 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if l.TotalStrokes != basetype.Uint16Invalid {
+		b = append(b, `"totalStrokes":`...)
+		b = strconv.AppendUint(b, uint64(l.TotalStrokes), 10)
+		b = append(b, ',')
+	}
+	if l.AvgSwimmingCadence != basetype.Uint8Invalid {
+		b = append(b, `"avgSwimmingCadence":`...)
+		b = strconv.AppendUint(b, uint64(l.AvgSwimmingCadence), 10)
+		b = append(b, ',')
+	}
+	if pace := l.Pace(); !math.IsNaN(pace) {
+		b = append(b, `"pace":`...)
+		b = strconv.AppendFloat(b, pace, 'f', 1, 64)
+		b = append(b, ',')
+	}
+	if swolf := l.SWOLF(); !math.IsNaN(swolf) {
+		b = append(b, `"swolf":`...)
+		b = strconv.AppendFloat(b, swolf, 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if b[len(b)-1] == ',' {
+		b = b[:len(b)-1]
+	}
+	return append(b, '}')
+}
@@ -0,0 +1,195 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+)
+
+// MeanMaxDurations is the default durations of the mean-maximal curve.
+var MeanMaxDurations = []time.Duration{
+	1 * time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	1 * time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 20 * time.Minute, 30 * time.Minute,
+	1 * time.Hour, 90 * time.Minute, 2 * time.Hour,
+}
+
+// BestEffortDistances is the default distances of the best efforts in meters: 400m, 1km, 1 mile, 5km, 10km,
+// half marathon and marathon.
+var BestEffortDistances = []float64{400, 1000, 1609.344, 5000, 10000, 21097.5, 42195}
+
+// MeanMax is a mean-maximal curve: the best average value sustained for each duration.
+type MeanMax struct {
+	Durations []time.Duration
+	Values    []float64 // NaN if the data is shorter than the duration.
+}
+
+// NewMeanMaxPower creates mean-maximal power curve from records.
+func NewMeanMaxPower(records []Record, durations []time.Duration) MeanMax {
+	return newMeanMax(secondSeries(records, func(rec *Record) float64 {
+		if rec.Power == basetype.Uint16Invalid {
+			return math.NaN()
+		}
+		return float64(rec.Power)
+	}), durations)
+}
+
+// NewMeanMaxHeartRate creates mean-maximal heart rate curve from records.
+func NewMeanMaxHeartRate(records []Record, durations []time.Duration) MeanMax {
+	return newMeanMax(secondSeries(records, func(rec *Record) float64 {
+		if rec.HeartRate == basetype.Uint8Invalid {
+			return math.NaN()
+		}
+		return float64(rec.HeartRate)
+	}), durations)
+}
+
+// newMeanMax calculates mean-maximal curve from 1 second series using sliding window,
+// a window never spans a gap (NaN), so the value is only sustained within continuous data.
+func newMeanMax(series []float64, durations []time.Duration) MeanMax {
+	mm := MeanMax{
+		Durations: durations,
+		Values:    make([]float64, len(durations)),
+	}
+
+	for i, d := range durations {
+		window := int(d / time.Second)
+		best := math.NaN()
+
+		var sum float64
+		var n int // number of continuous values in current window
+		for j := range series {
+			if math.IsNaN(series[j]) {
+				sum, n = 0, 0
+				continue
+			}
+			sum += series[j]
+			n++
+			if n > window {
+				sum -= series[j-window]
+				n = window
+			}
+			if n == window && window > 0 {
+				if avg := sum / float64(window); math.IsNaN(best) || avg > best {
+					best = avg
+				}
+			}
+		}
+		mm.Values[i] = best
+	}
+
+	return mm
+}
+
+// Merge merges other curve into mm by taking the max value of each duration, both curves should have the same durations.
+// It's used to build a curve across several activities, e.g. season curve.
+func (mm *MeanMax) Merge(other MeanMax) {
+	if len(mm.Durations) == 0 {
+		mm.Durations = other.Durations
+		mm.Values = make([]float64, len(other.Values))
+		for i := range mm.Values {
+			mm.Values[i] = math.NaN()
+		}
+	}
+	for i := range mm.Values {
+		if i >= len(other.Values) || math.IsNaN(other.Values[i]) {
+			continue
+		}
+		if math.IsNaN(mm.Values[i]) || other.Values[i] > mm.Values[i] {
+			mm.Values[i] = other.Values[i]
+		}
+	}
+}
+
+// MarshalAppendJSON appends the JSON format encoding of MeanMax to b, returning the result.
+// Durations are serialized in seconds and NaN values are serialized as null.
+func (mm *MeanMax) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '[')
+	for i := range mm.Durations {
+		b = append(b, `{"duration":`...)
+		b = strconv.AppendFloat(b, mm.Durations[i].Seconds(), 'g', -1, 64)
+		b = append(b, `,"value":`...)
+		if math.IsNaN(mm.Values[i]) {
+			b = append(b, "null"...)
+		} else {
+			b = strconv.AppendFloat(b, mm.Values[i], 'f', 1, 64)
+		}
+		b = append(b, '}')
+		if i != len(mm.Durations)-1 {
+			b = append(b, ',')
+		}
+	}
+	return append(b, ']')
+}
+
+// BestEffort is the fastest elapsed time to cover a distance.
+type BestEffort struct {
+	Distance  float64       // in meters
+	Duration  time.Duration // zero if the distance is never covered.
+	StartTime time.Time
+}
+
+// NewBestEfforts finds the fastest elapsed time to cover each distance in meters from records using two pointers,
+// records without timestamp or distance are skipped.
+func NewBestEfforts(records []Record, distances []float64) []BestEffort {
+	indexes := make([]int, 0, len(records))
+	for i := range records {
+		if !records[i].Timestamp.IsZero() && records[i].Distance != basetype.Uint32Invalid {
+			indexes = append(indexes, i)
+		}
+	}
+
+	bestEfforts := make([]BestEffort, len(distances))
+	for i, distance := range distances {
+		bestEfforts[i].Distance = distance
+
+		var start int
+		for end := range indexes {
+			endRec := &records[indexes[end]]
+			// Shrink the window while it still covers the distance.
+			for start+1 < end && endRec.DistanceScaled()-records[indexes[start+1]].DistanceScaled() >= distance {
+				start++
+			}
+			startRec := &records[indexes[start]]
+			if endRec.DistanceScaled()-startRec.DistanceScaled() < distance {
+				continue
+			}
+			d := endRec.Timestamp.Sub(startRec.Timestamp)
+			if bestEfforts[i].Duration == 0 || d < bestEfforts[i].Duration {
+				bestEfforts[i].Duration = d
+				bestEfforts[i].StartTime = startRec.Timestamp
+			}
+		}
+	}
+
+	return bestEfforts
+}
+
+// MarshalAppendJSON appends the JSON format encoding of BestEffort to b, returning the result.
+func (e *BestEffort) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"distance":`...)
+	b = strconv.AppendFloat(b, e.Distance, 'g', -1, 64)
+	if e.Duration != 0 {
+		b = append(b, `,"duration":`...)
+		b = strconv.AppendFloat(b, e.Duration.Seconds(), 'g', -1, 64)
+		b = append(b, `,"startTime":`...)
+		b = strconv.AppendQuote(b, e.StartTime.Format(time.RFC3339))
+	}
+	return append(b, '}')
+}
@@ -0,0 +1,142 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/openivity/activity-service/activity"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newRecords creates records whose timestamps are offset by the given seconds from t0,
+// fill sets the other fields of the i-th record.
+func newRecords(offsets []int, fill func(i int, rec *mesgdef.Record)) []activity.Record {
+	records := make([]activity.Record, len(offsets))
+	for i, offset := range offsets {
+		rec := mesgdef.NewRecord(nil).SetTimestamp(t0.Add(time.Duration(offset) * time.Second))
+		if fill != nil {
+			fill(i, rec)
+		}
+		records[i] = activity.CreateRecord(rec)
+	}
+	return records
+}
+
+func TestNewMeanMaxPower(t *testing.T) {
+	durations := []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second, 5 * time.Second}
+
+	tt := []struct {
+		name     string
+		offsets  []int
+		powers   []uint16
+		expected []float64
+	}{
+		{
+			name:     "continuous",
+			offsets:  []int{0, 1, 2, 3, 4},
+			powers:   []uint16{100, 200, 300, 400, 0},
+			expected: []float64{400, 350, 300, 250, math.NaN()},
+		},
+		{
+			name:     "value is held until the next record",
+			offsets:  []int{0, 2, 4},
+			powers:   []uint16{100, 200, 0},
+			expected: []float64{200, 200, 166.66666666666666, 150, math.NaN()},
+		},
+		{
+			name:     "window never spans a gap",
+			offsets:  []int{0, 1, 2, 200, 201, 202},
+			powers:   []uint16{300, 300, 300, 50, 50, 50},
+			expected: []float64{300, 300, math.NaN(), math.NaN(), math.NaN()},
+		},
+		{
+			name:     "no records",
+			expected: []float64{math.NaN(), math.NaN(), math.NaN(), math.NaN(), math.NaN()},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			records := newRecords(tc.offsets, func(i int, rec *mesgdef.Record) { rec.SetPower(tc.powers[i]) })
+			mm := activity.NewMeanMaxPower(records, durations)
+			for i := range durations {
+				if !equalFloat(mm.Values[i], tc.expected[i]) {
+					t.Fatalf("%s: expected: %g, got: %g", durations[i], tc.expected[i], mm.Values[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMeanMaxMerge(t *testing.T) {
+	durations := []time.Duration{1 * time.Second, 5 * time.Second, 10 * time.Second}
+
+	var mm activity.MeanMax
+	mm.Merge(activity.MeanMax{Durations: durations, Values: []float64{300, math.NaN(), math.NaN()}})
+	mm.Merge(activity.MeanMax{Durations: durations, Values: []float64{250, 200, math.NaN()}})
+
+	expected := []float64{300, 200, math.NaN()}
+	for i := range durations {
+		if !equalFloat(mm.Values[i], expected[i]) {
+			t.Fatalf("%s: expected: %g, got: %g", durations[i], expected[i], mm.Values[i])
+		}
+	}
+}
+
+func TestNewBestEfforts(t *testing.T) {
+	// 0m at 0s, 50m at 10s, 150m at 20s, 200m at 30s and 250m at 40s.
+	records := newRecords([]int{0, 10, 20, 30, 40}, func(i int, rec *mesgdef.Record) {
+		rec.SetDistanceScaled([]float64{0, 50, 150, 200, 250}[i])
+	})
+
+	tt := []struct {
+		distance  float64
+		duration  time.Duration
+		startTime time.Time
+	}{
+		{distance: 100, duration: 10 * time.Second, startTime: t0.Add(10 * time.Second)},
+		{distance: 200, duration: 30 * time.Second, startTime: t0},
+		{distance: 250, duration: 40 * time.Second, startTime: t0},
+		{distance: 1000, duration: 0}, // never covered
+	}
+
+	distances := make([]float64, len(tt))
+	for i := range tt {
+		distances[i] = tt[i].distance
+	}
+
+	bestEfforts := activity.NewBestEfforts(records, distances)
+	for i, tc := range tt {
+		e := bestEfforts[i]
+		if e.Distance != tc.distance || e.Duration != tc.duration || !e.StartTime.Equal(tc.startTime) {
+			t.Fatalf("%gm: expected: %s at %s, got: %s at %s",
+				tc.distance, tc.duration, tc.startTime, e.Duration, e.StartTime)
+		}
+	}
+}
+
+// equalFloat reports whether a and b are equal within 1e-9, NaN is only equal to NaN.
+func equalFloat(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) < 1e-9
+}
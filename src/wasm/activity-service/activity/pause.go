@@ -0,0 +1,151 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// Pause is a stationary period where the device keeps recording while the athlete is not moving.
+type Pause struct {
+	StartIndex int       // Index of the first stationary record.
+	EndIndex   int       // Index of the record where the athlete start moving again, len(records) if never resumed.
+	StartTime  time.Time // Timestamp of the first stationary record.
+	EndTime    time.Time // Timestamp of the record where the athlete start moving again or the last timestamp if never resumed.
+}
+
+// Duration returns the duration of the pause.
+func (p *Pause) Duration() time.Duration {
+	return p.EndTime.Sub(p.StartTime)
+}
+
+// DetectPauses detects stationary periods in the given records using the same moving-speed rules as TotalMovingTime,
+// so the sum of all pauses' duration is the difference between elapsed time and moving time.
+// It returns nil when records have no speed at all (e.g. indoor activity), since we can not tell whether it's moving.
+func DetectPauses(records []Record, sport typedef.Sport, sm Summarizer) []Pause {
+	var hasSpeed bool
+	for i := range records {
+		if records[i].Speed != basetype.Uint16Invalid {
+			hasSpeed = true
+			break
+		}
+	}
+	if !hasSpeed {
+		return nil
+	}
+
+	var pauses []Pause
+	var cur *Pause
+	var lastTimestamp time.Time
+	for i := range records {
+		rec := &records[i]
+		if rec.Timestamp.IsZero() {
+			continue
+		}
+		lastTimestamp = rec.Timestamp
+
+		if sm.IsConsideredMoving(sport, rec.SpeedScaled()) {
+			if cur != nil {
+				cur.EndIndex = i
+				cur.EndTime = rec.Timestamp
+				pauses = append(pauses, *cur)
+				cur = nil
+			}
+			continue
+		}
+
+		if cur == nil {
+			cur = &Pause{StartIndex: i, StartTime: rec.Timestamp}
+		}
+	}
+
+	if cur != nil && lastTimestamp.After(cur.StartTime) {
+		cur.EndIndex = len(records)
+		cur.EndTime = lastTimestamp
+		pauses = append(pauses, *cur)
+	}
+
+	return pauses
+}
+
+// PausedDuration returns total duration of the given pauses that overlap with the time window between start and end.
+func PausedDuration(pauses []Pause, start, end time.Time) time.Duration {
+	var total time.Duration
+	for i := range pauses {
+		p := &pauses[i]
+		s, e := p.StartTime, p.EndTime
+		if s.Before(start) {
+			s = start
+		}
+		if !end.IsZero() && e.After(end) {
+			e = end
+		}
+		if e.After(s) {
+			total += e.Sub(s)
+		}
+	}
+	return total
+}
+
+// MovingRange returns the index range [start, end] of records excluding the stationary head and tail, using the
+// same moving-speed rules as TotalMovingTime. The last stationary record of the head and the first stationary record
+// of the tail are kept as the departure and arrival points. It returns false if the records are never moving
+// (including when records have no speed at all) or the range has only 1 record, since there is nothing to keep.
+func MovingRange(records []Record, sport typedef.Sport, sm Summarizer) (start, end int, ok bool) {
+	isMoving := func(rec *Record) bool {
+		return !rec.Timestamp.IsZero() && sm.IsConsideredMoving(sport, rec.SpeedScaled())
+	}
+
+	first, last := -1, -1
+	for i := range records {
+		if isMoving(&records[i]) {
+			first = i
+			break
+		}
+	}
+	if first == -1 {
+		return 0, 0, false
+	}
+	for i := len(records) - 1; i >= first; i-- {
+		if isMoving(&records[i]) {
+			last = i
+			break
+		}
+	}
+
+	start, end = first, last
+	for i := first - 1; i >= 0; i-- {
+		if !records[i].Timestamp.IsZero() {
+			start = i
+			break
+		}
+	}
+	for i := last + 1; i < len(records); i++ {
+		if !records[i].Timestamp.IsZero() {
+			end = i
+			break
+		}
+	}
+
+	if start == end {
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
@@ -0,0 +1,145 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/openivity/activity-service/activity"
+)
+
+func TestDetectPauses(t *testing.T) {
+	sm := activity.NewPreprocessor().Summarizer()
+
+	tt := []struct {
+		name     string
+		speeds   []float64 // -1 means no speed.
+		expected []activity.Pause
+	}{
+		{
+			name:   "pause in the middle",
+			speeds: []float64{3, 3, 0, 0, 3, 3},
+			expected: []activity.Pause{
+				{StartIndex: 2, EndIndex: 4, StartTime: t0.Add(2 * time.Second), EndTime: t0.Add(4 * time.Second)},
+			},
+		},
+		{
+			name:   "never resumed",
+			speeds: []float64{3, 3, 0, 0},
+			expected: []activity.Pause{
+				{StartIndex: 2, EndIndex: 4, StartTime: t0.Add(2 * time.Second), EndTime: t0.Add(3 * time.Second)},
+			},
+		},
+		{
+			name:   "no speed",
+			speeds: []float64{-1, -1, -1},
+		},
+		{
+			name:   "always moving",
+			speeds: []float64{3, 3, 3},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			records := newRecordsWithSpeeds(tc.speeds)
+			pauses := activity.DetectPauses(records, typedef.SportRunning, sm)
+			if len(pauses) != len(tc.expected) {
+				t.Fatalf("expected: %d pauses, got: %d", len(tc.expected), len(pauses))
+			}
+			for i := range pauses {
+				if pauses[i] != tc.expected[i] {
+					t.Fatalf("[%d] expected: %+v, got: %+v", i, tc.expected[i], pauses[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMovingRange(t *testing.T) {
+	sm := activity.NewPreprocessor().Summarizer()
+
+	tt := []struct {
+		name          string
+		speeds        []float64 // -1 means no speed.
+		expectedStart int
+		expectedEnd   int
+		expectedOk    bool
+	}{
+		{
+			name:          "stationary head and tail",
+			speeds:        []float64{0, 0, 3, 3, 3, 0, 0},
+			expectedStart: 1,
+			expectedEnd:   5,
+			expectedOk:    true,
+		},
+		{
+			name:          "always moving",
+			speeds:        []float64{3, 3, 3},
+			expectedStart: 0,
+			expectedEnd:   2,
+			expectedOk:    true,
+		},
+		{
+			name:          "single moving record in the middle",
+			speeds:        []float64{0, 3, 0},
+			expectedStart: 0,
+			expectedEnd:   2,
+			expectedOk:    true,
+		},
+		{
+			name:   "single moving record",
+			speeds: []float64{3},
+		},
+		{
+			name:   "all stationary",
+			speeds: []float64{0, 0, 0},
+		},
+		{
+			name:   "no speed",
+			speeds: []float64{-1, -1, -1},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			records := newRecordsWithSpeeds(tc.speeds)
+			start, end, ok := activity.MovingRange(records, typedef.SportRunning, sm)
+			if ok != tc.expectedOk {
+				t.Fatalf("expected ok: %t, got: %t", tc.expectedOk, ok)
+			}
+			if start != tc.expectedStart || end != tc.expectedEnd {
+				t.Fatalf("expected: [%d, %d], got: [%d, %d]", tc.expectedStart, tc.expectedEnd, start, end)
+			}
+		})
+	}
+}
+
+// newRecordsWithSpeeds creates 1s records with the given speeds in m/s, -1 means no speed.
+func newRecordsWithSpeeds(speeds []float64) []activity.Record {
+	offsets := make([]int, len(speeds))
+	for i := range offsets {
+		offsets[i] = i
+	}
+	return newRecords(offsets, func(i int, rec *mesgdef.Record) {
+		if speeds[i] >= 0 {
+			rec.SetSpeedScaled(speeds[i])
+		}
+	})
+}
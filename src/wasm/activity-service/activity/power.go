@@ -0,0 +1,150 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+)
+
+// normalizedPowerWindow is the rolling average window used for calculating Normalized Power.
+const normalizedPowerWindow = 30 // seconds
+
+// PowerMetrics is power analytics calculated from records.
+type PowerMetrics struct {
+	AvgPower         float64       // in watts
+	NormalizedPower  float64       // in watts, NaN if the power data is shorter than 30 seconds.
+	VariabilityIndex float64       // NormalizedPower / AvgPower
+	Work             float64       // in joules
+	Duration         time.Duration // Duration of the power data, excluding pauses.
+}
+
+// NewPowerMetrics calculates power metrics from records. It returns false if records have no power.
+func NewPowerMetrics(records []Record) (PowerMetrics, bool) {
+	series := secondSeries(records, func(rec *Record) float64 {
+		if rec.Power == basetype.Uint16Invalid {
+			return math.NaN()
+		}
+		return float64(rec.Power)
+	})
+
+	powers := make([]float64, 0, len(series))
+	for _, v := range series {
+		if !math.IsNaN(v) {
+			powers = append(powers, v)
+		}
+	}
+	if len(powers) == 0 {
+		return PowerMetrics{}, false
+	}
+
+	var work float64
+	for _, v := range powers {
+		work += v // 1 watt in 1 second is 1 joule.
+	}
+
+	pm := PowerMetrics{
+		AvgPower:         work / float64(len(powers)),
+		NormalizedPower:  normalizedPower(powers),
+		VariabilityIndex: math.NaN(),
+		Work:             work,
+		Duration:         time.Duration(len(powers)) * time.Second,
+	}
+	if pm.AvgPower != 0 {
+		pm.VariabilityIndex = pm.NormalizedPower / pm.AvgPower
+	}
+
+	return pm, true
+}
+
+// IntensityFactor returns Intensity Factor (NormalizedPower / FTP) for the given Functional Threshold Power in watts.
+func (p *PowerMetrics) IntensityFactor(ftp float64) float64 {
+	if ftp <= 0 {
+		return math.NaN()
+	}
+	return p.NormalizedPower / ftp
+}
+
+// TrainingStressScore returns Training Stress Score for the given Functional Threshold Power in watts,
+// 100 TSS is equal to riding 1 hour at FTP.
+func (p *PowerMetrics) TrainingStressScore(ftp float64) float64 {
+	if ftp <= 0 {
+		return math.NaN()
+	}
+	intensityFactor := p.IntensityFactor(ftp)
+	return p.Duration.Seconds() * p.NormalizedPower * intensityFactor / (ftp * 3600) * 100
+}
+
+// normalizedPower calculates Normalized Power from 1 second power series: the 4th root of the mean of
+// 30 seconds rolling average power raised to the 4th power.
+func normalizedPower(powers []float64) float64 {
+	if len(powers) < normalizedPowerWindow {
+		return math.NaN()
+	}
+
+	var sum, total float64
+	var count int
+	for i := range powers {
+		sum += powers[i]
+		if i >= normalizedPowerWindow {
+			sum -= powers[i-normalizedPowerWindow]
+		}
+		if i < normalizedPowerWindow-1 {
+			continue
+		}
+		avg := sum / normalizedPowerWindow
+		total += avg * avg * avg * avg
+		count++
+	}
+
+	return math.Pow(total/float64(count), 0.25)
+}
+
+// secondSeries resamples records' value into 1 value per second, each record's value is held until the next record.
+// Interval between two records longer than maxRecordInterval is considered as a pause and is filled with NaN,
+// so is the record without value.
+func secondSeries(records []Record, value func(rec *Record) float64) []float64 {
+	var series []float64
+	var prev *Record
+	for i := range records {
+		rec := &records[i]
+		if rec.Timestamp.IsZero() {
+			continue
+		}
+		if prev == nil {
+			prev = rec
+			continue
+		}
+
+		d := rec.Timestamp.Sub(prev.Timestamp)
+		n := int(d / time.Second)
+		if n <= 0 {
+			continue
+		}
+
+		v := value(prev)
+		if d > maxRecordInterval {
+			v = math.NaN()
+		}
+		for j := 0; j < n; j++ {
+			series = append(series, v)
+		}
+		prev = rec
+	}
+	return series
+}
@@ -17,11 +17,15 @@ package activity
 
 import (
 	"math"
+	"time"
 
 	"github.com/muktihari/fit/kit/scaleoffset"
+	"github.com/muktihari/fit/kit/semicircles"
 	"github.com/muktihari/fit/profile/basetype"
 	"github.com/muktihari/fit/profile/typedef"
+	"github.com/openivity/activity-service/dem"
 	"github.com/openivity/activity-service/geomath"
+	"github.com/openivity/activity-service/kalman"
 )
 
 // Preprocessor is a preprocessor for improving activity's data such as smoothing the elevation and calculating slope/gradient.
@@ -32,12 +36,25 @@ type Preprocessor struct {
 type options struct {
 	smoothingElevDistance  float64 // in meters
 	calculateGradeDistance float64 // in meters
+	maxAcceleration        float64 // in m/s²
+	maxConsecutiveOutliers int
+	smoothingPosition      bool
+	processNoise           float64 // in m/s²
+	measurementNoise       float64 // in meters
+	elevationModel         *dem.Model
+	elevationFillOnly      bool
+	disabledStages         Stage
+	summarizer             Summarizer
 }
 
 func defaultOptions() *options {
 	return &options{
 		smoothingElevDistance:  30,
 		calculateGradeDistance: 100,
+		maxAcceleration:        10,
+		maxConsecutiveOutliers: 10,
+		processNoise:           1,
+		measurementNoise:       5,
 	}
 }
 
@@ -63,6 +80,76 @@ func WithCalculateDistance(d float64) Option {
 	})
 }
 
+// WithMaxAcceleration sets maximum acceleration between two consecutive positions before it's considered as GPS error.
+func WithMaxAcceleration(a float64) Option {
+	return fnApply(func(o *options) {
+		if a > 0 {
+			o.maxAcceleration = a
+		}
+	})
+}
+
+// WithSmoothingPosition enables smoothing positions and elevation using Kalman filter.
+func WithSmoothingPosition(enabled bool) Option {
+	return fnApply(func(o *options) {
+		o.smoothingPosition = enabled
+	})
+}
+
+// WithSmoothingPositionNoise sets the Kalman filter's process noise (standard deviation of acceleration in m/s²)
+// and measurement noise (standard deviation of GPS or altimeter accuracy in meters).
+func WithSmoothingPositionNoise(processNoise, measurementNoise float64) Option {
+	return fnApply(func(o *options) {
+		if processNoise > 0 {
+			o.processNoise = processNoise
+		}
+		if measurementNoise > 0 {
+			o.measurementNoise = measurementNoise
+		}
+	})
+}
+
+// WithElevationModel sets digital elevation model used to correct records' altitude,
+// if fillOnly is true, only records without altitude will be filled.
+func WithElevationModel(model *dem.Model, fillOnly bool) Option {
+	return fnApply(func(o *options) {
+		o.elevationModel = model
+		o.elevationFillOnly = fillOnly
+	})
+}
+
+// Stage is a preprocessing stage that can be disabled using WithDisabledStages. Multiple stages can be combined using bitwise OR.
+type Stage uint8
+
+const (
+	StageRemoveGPSOutliers  Stage = 1 << iota // Detect and fix impossible jumps in positions.
+	StageCorrectElevation                     // Correct altitude from digital elevation model.
+	StageSmoothingElevation                   // Smooth altitude before calculating ascent, descent and grade.
+	StageCalculateGrade                       // Calculate slope/gradient.
+)
+
+// WithDisabledStages disables given preprocessing stages.
+func WithDisabledStages(stages Stage) Option {
+	return fnApply(func(o *options) {
+		o.disabledStages = stages
+	})
+}
+
+// WithToleranceMovingSpeeds overrides the default tolerance moving speed (in m/s) of the given sports,
+// sports that are not specified use the default tolerance.
+func WithToleranceMovingSpeeds(speeds map[typedef.Sport]float64) Option {
+	return fnApply(func(o *options) {
+		o.summarizer.ToleranceMovingSpeeds = speeds
+	})
+}
+
+// WithElevationGain sets the algorithm to calculate total ascent and descent, nil means ElevationGainSmoothed.
+func WithElevationGain(eg ElevationGain) Option {
+	return fnApply(func(o *options) {
+		o.summarizer.ElevationGain = eg
+	})
+}
+
 // NewPreprocessor creates new preprocessor.
 func NewPreprocessor(opts ...Option) *Preprocessor {
 	options := defaultOptions()
@@ -73,6 +160,17 @@ func NewPreprocessor(opts ...Option) *Preprocessor {
 	return &Preprocessor{options: options}
 }
 
+// Reset resets preprocessor's options to default and applies the given options.
+func (p *Preprocessor) Reset(opts ...Option) {
+	p.options = defaultOptions()
+	for i := range opts {
+		opts[i].apply(p.options)
+	}
+}
+
+// Summarizer returns the summarizer configured by preprocessor's options.
+func (p *Preprocessor) Summarizer() Summarizer { return p.options.summarizer }
+
 // AggregateByTimestamp aggregates fields with the same timestamp if any and return new slice of record.
 // The FIT files produced by Strava is splitting values into multiple records with the same timestamp, so
 // we think it's possible for other platforms/devices to produce similiar files.
@@ -154,7 +252,257 @@ func (p *Preprocessor) AggregateByTimestamp(records []Record) []Record {
 	return records
 }
 
+// RemoveGPSOutliers detects impossible jumps in positions and returns the number of positions fixed.
+// A position is considered as an outlier when the speed implied from previous valid position is beyond
+// MaxPlausibleSpeed of the given sport or the implied acceleration is beyond maximum acceleration.
+// Outliers are interpolated from its surrounding valid positions, or removed if it's not surrounded by any.
+// Distance and speed recorded by the device (e.g. FIT) are recalculated on the fixed records, see correctDistanceAndSpeed.
+// This should be called before CalculateDistanceAndSpeed so the outliers do not contribute to the distance.
+func (p *Preprocessor) RemoveGPSOutliers(sport typedef.Sport, records []Record) (fixed int) {
+	if p.options.disabledStages&StageRemoveGPSOutliers != 0 {
+		return 0
+	}
+
+	maxSpeed := MaxPlausibleSpeed(sport)
+	outliers := make([]bool, len(records))
+
+	var prev = -1
+	var prevSpeed = math.NaN()
+	var consecutive int
+	for i := range records {
+		rec := &records[i]
+		if rec.PositionLat == basetype.Sint32Invalid || rec.PositionLong == basetype.Sint32Invalid ||
+			rec.Timestamp.IsZero() {
+			continue
+		}
+		if prev == -1 {
+			prev = i
+			continue
+		}
+
+		elapsed := rec.Timestamp.Sub(records[prev].Timestamp).Seconds()
+		if elapsed <= 0 {
+			continue
+		}
+
+		d := geomath.HaversineDistance(
+			records[prev].PositionLatDegrees(),
+			records[prev].PositionLongDegrees(),
+			rec.PositionLatDegrees(),
+			rec.PositionLongDegrees(),
+		)
+		speed := d / elapsed
+		acceleration := (speed - prevSpeed) / elapsed // NaN for the first segment, so it's never an outlier.
+
+		if speed > maxSpeed || acceleration > p.options.maxAcceleration {
+			if consecutive < p.options.maxConsecutiveOutliers {
+				outliers[i] = true
+				consecutive++
+				continue
+			}
+			// When outliers keep going, it's more likely the previous valid position is the one that jump,
+			// let's just trust current position rather than discarding the whole track.
+			speed = math.NaN()
+		}
+
+		prev, prevSpeed, consecutive = i, speed, 0
+	}
+
+	for i := range records {
+		if !outliers[i] {
+			continue
+		}
+		rec := &records[i]
+		fixed++
+
+		var before, after = -1, -1
+		for j := i - 1; j >= 0; j-- {
+			if !outliers[j] && !records[j].Timestamp.IsZero() &&
+				records[j].PositionLat != basetype.Sint32Invalid && records[j].PositionLong != basetype.Sint32Invalid {
+				before = j
+				break
+			}
+		}
+		for j := i + 1; j < len(records); j++ {
+			if !outliers[j] && !records[j].Timestamp.IsZero() &&
+				records[j].PositionLat != basetype.Sint32Invalid && records[j].PositionLong != basetype.Sint32Invalid {
+				after = j
+				break
+			}
+		}
+
+		if before == -1 || after == -1 {
+			rec.PositionLat = basetype.Sint32Invalid
+			rec.PositionLong = basetype.Sint32Invalid
+			continue
+		}
+
+		prev, next := &records[before], &records[after]
+		ratio := rec.Timestamp.Sub(prev.Timestamp).Seconds() / next.Timestamp.Sub(prev.Timestamp).Seconds()
+		rec.PositionLat = prev.PositionLat + int32(math.Round(float64(int64(next.PositionLat)-int64(prev.PositionLat))*ratio))
+		rec.PositionLong = prev.PositionLong + int32(math.Round(float64(int64(next.PositionLong)-int64(prev.PositionLong))*ratio))
+	}
+
+	if fixed > 0 {
+		correctDistanceAndSpeed(records, outliers)
+	}
+
+	return fixed
+}
+
+// correctDistanceAndSpeed recalculates recorded distance and speed of the fixed records and the record right after
+// each of them from their positions, since those values are derived from the outliers. The spike is removed from the
+// accumulated distance of the later records as well. Records without recorded distance are left for
+// CalculateDistanceAndSpeed.
+func correctDistanceAndSpeed(records []Record, outliers []bool) {
+	var offset float64 // Accumulated distance of the spikes in meters.
+	var prev = -1      // Index of the previous record having position.
+	var affected bool  // Whether current record is an outlier or the record right after outliers.
+	for i := range records {
+		rec := &records[i]
+		affected = affected || outliers[i]
+
+		hasPosition := rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid &&
+			!rec.Timestamp.IsZero()
+		if rec.Distance != basetype.Uint32Invalid {
+			distance := rec.DistanceScaled() - offset
+			if affected && hasPosition && prev != -1 && records[prev].Distance != basetype.Uint32Invalid {
+				d := geomath.HaversineDistance(
+					records[prev].PositionLatDegrees(),
+					records[prev].PositionLongDegrees(),
+					rec.PositionLatDegrees(),
+					rec.PositionLongDegrees(),
+				)
+				distance = records[prev].DistanceScaled() + d
+				offset = rec.DistanceScaled() - distance
+
+				if elapsed := rec.Timestamp.Sub(records[prev].Timestamp).Seconds(); elapsed > 0 {
+					if rec.Speed != basetype.Uint16Invalid {
+						rec.Speed = uint16(scaleoffset.Discard(d/elapsed, 1000, 0))
+					}
+					if rec.EnhancedSpeed != basetype.Uint32Invalid {
+						rec.EnhancedSpeed = uint32(scaleoffset.Discard(d/elapsed, 1000, 0))
+					}
+				}
+			}
+			rec.Distance = uint32(scaleoffset.Discard(math.Max(distance, 0), 100, 0))
+		}
+
+		if hasPosition {
+			prev = i
+			affected = outliers[i] // Keep going until the record right after the outliers.
+		}
+	}
+}
+
+// SmoothingPosition smoothing positions using constant-velocity Kalman filter and RTS smoother over timestamps,
+// the result is stored in SmoothedPositionLat and SmoothedPositionLong while the raw positions are kept as it is.
+// It does nothing unless enabled using WithSmoothingPosition option.
+func (p *Preprocessor) SmoothingPosition(records []Record) {
+	if !p.options.smoothingPosition {
+		return
+	}
+
+	// Project positions into a plane (in meters) using the first position's latitude as the reference,
+	// the distortion is negligible for the size of an activity.
+	var lat0 = math.NaN()
+	for i := range records {
+		if records[i].PositionLat != basetype.Sint32Invalid && records[i].PositionLong != basetype.Sint32Invalid {
+			lat0 = records[i].PositionLatDegrees()
+			break
+		}
+	}
+	if math.IsNaN(lat0) {
+		return
+	}
+	metersPerDegreeLat := geomath.MetersPerDegree
+	metersPerDegreeLong := geomath.MetersPerDegree * math.Cos(lat0*math.Pi/180)
+
+	ts, xs, ys, indexes := timeSeries(records)
+	for n, i := range indexes {
+		rec := &records[i]
+		xs[n], ys[n] = math.NaN(), math.NaN()
+		if rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid {
+			xs[n] = rec.PositionLongDegrees() * metersPerDegreeLong
+			ys[n] = rec.PositionLatDegrees() * metersPerDegreeLat
+		}
+	}
+
+	xs = kalman.Smooth(ts, xs, p.options.processNoise, p.options.measurementNoise)
+	ys = kalman.Smooth(ts, ys, p.options.processNoise, p.options.measurementNoise)
+
+	for n, i := range indexes {
+		rec := &records[i]
+		if rec.PositionLat == basetype.Sint32Invalid || rec.PositionLong == basetype.Sint32Invalid {
+			continue // Only smooth existing positions.
+		}
+		rec.SmoothedPositionLat = semicircles.ToSemicircles(ys[n] / metersPerDegreeLat)
+		rec.SmoothedPositionLong = semicircles.ToSemicircles(xs[n] / metersPerDegreeLong)
+	}
+}
+
+// CorrectElevation replaces records' altitude with the elevation sampled from the digital elevation model,
+// records outside the model's coverage are left as it is. It does nothing unless WithElevationModel is specified.
+func (p *Preprocessor) CorrectElevation(records []Record) {
+	if p.options.disabledStages&StageCorrectElevation != 0 {
+		return
+	}
+	if p.options.elevationModel == nil || p.options.elevationModel.Len() == 0 {
+		return
+	}
+
+	for i := range records {
+		rec := &records[i]
+		if p.options.elevationFillOnly &&
+			(rec.Altitude != basetype.Uint16Invalid || rec.EnhancedAltitude != basetype.Uint32Invalid) {
+			continue
+		}
+
+		lat, long, ok := rec.positionDegrees()
+		if !ok {
+			continue
+		}
+		elevation, ok := p.options.elevationModel.Elevation(lat, long)
+		if !ok {
+			continue
+		}
+
+		rec.SetAltitudeScaled(elevation)
+		rec.SetEnhancedAltitudeScaled(elevation)
+	}
+}
+
+// CalculateGradeAdjustedPace calculates grade-adjusted pace of records having pace using Minetti's energy cost model,
+// so it should be called after CalculateGrade and CalculatePace. It's only meaningful for sports having pace (see HasPace).
+func (p *Preprocessor) CalculateGradeAdjustedPace(records []Record) {
+	for i := range records {
+		rec := &records[i]
+		if math.IsNaN(rec.Pace) {
+			continue
+		}
+		rec.GradeAdjustedPace = rec.Pace / GradeAdjustedFactor(rec.Grade)
+	}
+}
+
+// timeSeries returns timestamps (in seconds relative to the first timestamp) of records that have timestamp
+// along with its indexes, plus two empty slices of the same length ready to be filled.
+func timeSeries(records []Record) (ts, xs, ys []float64, indexes []int) {
+	var begin time.Time
+	for i := range records {
+		if records[i].Timestamp.IsZero() {
+			continue
+		}
+		if begin.IsZero() {
+			begin = records[i].Timestamp
+		}
+		ts = append(ts, records[i].Timestamp.Sub(begin).Seconds())
+		indexes = append(indexes, i)
+	}
+	return ts, make([]float64, len(ts)), make([]float64, len(ts)), indexes
+}
+
 // CalculateDistanceAndSpeed calculates distance from latitude and longitude and speed when those values are missing.
+// Smoothed positions are used when available.
 func (p *Preprocessor) CalculateDistanceAndSpeed(records []Record) {
 	for i := 1; i < len(records); i++ {
 		rec := &records[i]
@@ -163,19 +511,14 @@ func (p *Preprocessor) CalculateDistanceAndSpeed(records []Record) {
 		// Calculate distance from two coordinates
 		var pointDistance float64
 		if rec.Distance == basetype.Uint32Invalid {
-			if rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid &&
-				prev.PositionLat != basetype.Sint32Invalid && prev.PositionLong != basetype.Sint32Invalid {
-
+			lat, long, ok := rec.positionDegrees()
+			prevLat, prevLong, prevOk := prev.positionDegrees()
+			if ok && prevOk {
 				var prevDist float64
 				if prev.Distance != basetype.Uint32Invalid {
 					prevDist = prev.DistanceScaled()
 				}
-				pointDistance = geomath.HaversineDistance(
-					rec.PositionLatDegrees(),
-					rec.PositionLongDegrees(),
-					prev.PositionLatDegrees(),
-					prev.PositionLongDegrees(),
-				)
+				pointDistance = geomath.HaversineDistance(lat, long, prevLat, prevLong)
 				rec.Distance = uint32(scaleoffset.Discard(prevDist+pointDistance, 100, 0))
 			}
 		} else if rec.Distance != basetype.Uint32Invalid && prev.Distance != basetype.Uint32Invalid {
@@ -193,7 +536,8 @@ func (p *Preprocessor) CalculateDistanceAndSpeed(records []Record) {
 	}
 }
 
-// SmoothingElevation smoothing elevation values using simple moving average.
+// SmoothingElevation smoothing elevation values using simple moving average,
+// or using Kalman filter over timestamps when WithSmoothingPosition option is enabled.
 func (p *Preprocessor) SmoothingElevation(records []Record) {
 	// Copy altitude value
 	for i := range records {
@@ -206,6 +550,24 @@ func (p *Preprocessor) SmoothingElevation(records []Record) {
 		}
 	}
 
+	if p.options.disabledStages&StageSmoothingElevation != 0 {
+		return // Use raw altitude as it is.
+	}
+
+	if p.options.smoothingPosition {
+		ts, zs, _, indexes := timeSeries(records)
+		for n, i := range indexes {
+			zs[n] = records[i].SmoothedAltitude
+		}
+		zs = kalman.Smooth(ts, zs, p.options.processNoise, p.options.measurementNoise)
+		for n, i := range indexes {
+			if !math.IsNaN(records[i].SmoothedAltitude) {
+				records[i].SmoothedAltitude = zs[n]
+			}
+		}
+		return
+	}
+
 	for i := range records {
 		rec := &records[i]
 		if rec.Distance == basetype.Uint32Invalid || math.IsNaN(rec.SmoothedAltitude) {
@@ -234,6 +596,10 @@ func (p *Preprocessor) SmoothingElevation(records []Record) {
 
 // CalculateGrade calculates grade percentage.
 func (p *Preprocessor) CalculateGrade(records []Record) {
+	if p.options.disabledStages&StageCalculateGrade != 0 {
+		return
+	}
+
 	for i := range records {
 		rec := &records[i]
 
@@ -298,7 +664,7 @@ func (p *Preprocessor) CalculatePace(sport typedef.Sport, records []Record) {
 		if math.IsNaN(speed) {
 			speed = rec.EnhancedSpeedScaled()
 		}
-		if !IsConsideredMoving(sport, speed) {
+		if !p.options.summarizer.IsConsideredMoving(sport, speed) {
 			continue
 		}
 
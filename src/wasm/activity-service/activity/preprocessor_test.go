@@ -0,0 +1,60 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/openivity/activity-service/activity"
+)
+
+func TestRemoveGPSOutliersCorrectsRecordedDistanceAndSpeed(t *testing.T) {
+	// Moving north at ~5.56 m/s (0.00005° per second), the 5th record jumps ~1.1 km away and back,
+	// the device's distance and speed include the jump.
+	const step = 0.00005
+	offsets := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	records := newRecords(offsets, func(i int, rec *mesgdef.Record) {
+		lat := 0.001 + float64(i)*step
+		distance := float64(i) * 5.56
+		speed := 5.56
+		if i == 5 {
+			lat += 0.01
+			speed = 1112
+		}
+		if i >= 5 {
+			distance += 2224
+		}
+		rec.SetPositionLatDegrees(lat).SetPositionLongDegrees(10).SetDistanceScaled(distance).SetSpeedScaled(speed)
+	})
+
+	fixed := activity.NewPreprocessor().RemoveGPSOutliers(typedef.SportRunning, records)
+	if fixed != 1 {
+		t.Fatalf("expected: 1 fixed position, got: %d", fixed)
+	}
+
+	for i := 1; i < len(records); i++ {
+		d := records[i].DistanceScaled() - records[i-1].DistanceScaled()
+		if math.Abs(d-5.56) > 0.1 {
+			t.Fatalf("[%d] expected distance increment: ~5.56, got: %g", i, d)
+		}
+		if speed := records[i].SpeedScaled(); math.Abs(speed-5.56) > 0.1 {
+			t.Fatalf("[%d] expected speed: ~5.56, got: %g", i, speed)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/muktihari/fit/kit/semicircles"
 	"github.com/muktihari/fit/profile/basetype"
 	"github.com/muktihari/fit/profile/mesgdef"
 )
@@ -28,9 +29,14 @@ import (
 type Record struct {
 	*mesgdef.Record
 
-	SmoothedAltitude float64 // Smoothed Altitude using our preprocessor algorithm.
-	Pace             float64
-	Grade            float64
+	SmoothedAltitude     float64 // Smoothed Altitude using our preprocessor algorithm.
+	SmoothedPositionLat  int32   // Smoothed PositionLat (in semicircles) using our preprocessor algorithm.
+	SmoothedPositionLong int32   // Smoothed PositionLong (in semicircles) using our preprocessor algorithm.
+	Pace                 float64
+	GradeAdjustedPace    float64 // Equivalent pace on flat ground, see GradeAdjustedFactor.
+	Grade                float64
+	WPrimeBalance        float64 // W' balance in joules, see CalculateWPrimeBalance.
+	Interpolated         bool    // Whether this record is not recorded by the device but interpolated by us.
 }
 
 // CreateRecord creates new record.
@@ -39,13 +45,28 @@ func CreateRecord(rec *mesgdef.Record) Record {
 		rec = mesgdef.NewRecord(nil)
 	}
 	return Record{
-		Record:           rec,
-		SmoothedAltitude: math.NaN(),
-		Pace:             math.NaN(),
-		Grade:            math.NaN(),
+		Record:               rec,
+		SmoothedAltitude:     math.NaN(),
+		SmoothedPositionLat:  basetype.Sint32Invalid,
+		SmoothedPositionLong: basetype.Sint32Invalid,
+		Pace:                 math.NaN(),
+		GradeAdjustedPace:    math.NaN(),
+		Grade:                math.NaN(),
+		WPrimeBalance:        math.NaN(),
 	}
 }
 
+// positionDegrees returns record's position in degrees, smoothed position takes precedence over the raw one.
+func (r *Record) positionDegrees() (lat, long float64, ok bool) {
+	if r.SmoothedPositionLat != basetype.Sint32Invalid && r.SmoothedPositionLong != basetype.Sint32Invalid {
+		return semicircles.ToDegrees(r.SmoothedPositionLat), semicircles.ToDegrees(r.SmoothedPositionLong), true
+	}
+	if r.PositionLat != basetype.Sint32Invalid && r.PositionLong != basetype.Sint32Invalid {
+		return r.PositionLatDegrees(), r.PositionLongDegrees(), true
+	}
+	return math.NaN(), math.NaN(), false
+}
+
 // MarshalAppendJSON appends the JSON format encoding of Record to b, returning the result.
 func (r *Record) MarshalAppendJSON(b []byte) []byte {
 	b = append(b, '{')
@@ -65,6 +86,16 @@ func (r *Record) MarshalAppendJSON(b []byte) []byte {
 		b = strconv.AppendFloat(b, r.PositionLongDegrees(), 'g', -1, 64)
 		b = append(b, ',')
 	}
+	if r.SmoothedPositionLat != basetype.Sint32Invalid {
+		b = append(b, `"smoothedPositionLat":`...)
+		b = strconv.AppendFloat(b, semicircles.ToDegrees(r.SmoothedPositionLat), 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if r.SmoothedPositionLong != basetype.Sint32Invalid {
+		b = append(b, `"smoothedPositionLong":`...)
+		b = strconv.AppendFloat(b, semicircles.ToDegrees(r.SmoothedPositionLong), 'g', -1, 64)
+		b = append(b, ',')
+	}
 	if r.Distance != basetype.Uint32Invalid {
 		b = append(b, `"distance":`...)
 		b = strconv.AppendFloat(b, r.DistanceScaled(), 'g', -1, 64)
@@ -120,9 +151,23 @@ func (r *Record) MarshalAppendJSON(b []byte) []byte {
 		b = strconv.AppendFloat(b, r.Pace, 'g', -1, 64)
 		b = append(b, ',')
 	}
+	if !math.IsNaN(r.GradeAdjustedPace) {
+		b = append(b, `"gradeAdjustedPace":`...)
+		b = strconv.AppendFloat(b, r.GradeAdjustedPace, 'g', -1, 64)
+		b = append(b, ',')
+	}
 	if !math.IsNaN(r.Grade) {
 		b = append(b, `"grade":`...)
 		b = strconv.AppendFloat(b, r.Grade, 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if !math.IsNaN(r.WPrimeBalance) {
+		b = append(b, `"wPrimeBalance":`...)
+		b = strconv.AppendFloat(b, r.WPrimeBalance, 'f', 0, 64)
+		b = append(b, ',')
+	}
+	if r.Interpolated {
+		b = append(b, `"interpolated":true`...)
 	}
 
 	if b[len(b)-1] == '{' {
@@ -0,0 +1,250 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/openivity/activity-service/geomath"
+)
+
+// DefaultSegmentRadius is the default radius in meters around segment's points for a record to be considered passing them.
+const DefaultSegmentRadius = 25.0
+
+// Segment is a user-defined section of a route, e.g. a local climb or a sprint stretch. The first and the last points
+// are the start and the end of the segment, the points in between are the path that should be followed.
+type Segment struct {
+	Name   string
+	Lats   []float64 // Points' latitude in degrees, at least 2 points: start and end.
+	Longs  []float64 // Points' longitude in degrees, len should match Lats.
+	Radius float64   // Radius in meters around the points, zero means DefaultSegmentRadius.
+}
+
+// IsValid reports whether the segment has start and end points.
+func (s *Segment) IsValid() bool {
+	return len(s.Lats) >= 2 && len(s.Lats) == len(s.Longs)
+}
+
+// Length returns segment's length in meters along its points.
+func (s *Segment) Length() float64 {
+	var length float64
+	for i := 1; i < len(s.Lats); i++ {
+		length += geomath.HaversineDistance(s.Lats[i-1], s.Longs[i-1], s.Lats[i], s.Longs[i])
+	}
+	return length
+}
+
+func (s *Segment) radius() float64 {
+	if s.Radius > 0 {
+		return s.Radius
+	}
+	return DefaultSegmentRadius
+}
+
+// SegmentEffort is a pass of a segment in the records.
+type SegmentEffort struct {
+	StartIndex   int // Index of the record closest to segment's start.
+	EndIndex     int // Index of the record closest to segment's end.
+	StartTime    time.Time
+	ElapsedTime  time.Duration
+	Distance     float64 // in meters.
+	AvgPower     float64 // NaN if records have no power.
+	AvgHeartRate float64 // NaN if records have no heart rate.
+}
+
+// MatchSegment finds every pass of the segment in the records. A pass starts at the record closest to segment's start
+// within the radius, follows segment's points in order and ends at the record closest to segment's end within
+// the radius. Passes that travel more than twice the segment's length are rejected (e.g. detours).
+func MatchSegment(records []Record, segment *Segment) []SegmentEffort {
+	if !segment.IsValid() {
+		return nil
+	}
+
+	n := len(segment.Lats)
+	radius := segment.radius()
+	maxDistance := 2*segment.Length() + 2*radius
+
+	distanceTo := func(i, point int) float64 {
+		lat, long, ok := records[i].positionDegrees()
+		if !ok {
+			return math.Inf(1)
+		}
+		return geomath.HaversineDistance(lat, long, segment.Lats[point], segment.Longs[point])
+	}
+
+	// closestWithin finds the first run of records within the radius of the point starting from index i,
+	// returning the index of the first and the closest record of the run, and the index after the run.
+	// It returns -1 as the closest if not found.
+	closestWithin := func(i, point int) (first, closest, next int) {
+		for ; i < len(records); i++ {
+			if distanceTo(i, point) <= radius {
+				break
+			}
+		}
+		if i == len(records) {
+			return i, -1, i
+		}
+		first, closest = i, i
+		for ; i < len(records); i++ {
+			d := distanceTo(i, point)
+			if math.IsInf(d, 1) {
+				continue // Record without position does not break the run.
+			}
+			if d > radius {
+				break
+			}
+			if d < distanceTo(closest, point) {
+				closest = i
+			}
+		}
+		return first, closest, i
+	}
+
+	var efforts []SegmentEffort
+	for cur := 0; cur < len(records); {
+		_, start, afterStart := closestWithin(cur, 0)
+		if start == -1 {
+			break
+		}
+		cur = afterStart
+
+		endFirst, end, _ := closestWithin(afterStart, n-1)
+		if end == -1 {
+			break
+		}
+
+		// Start from the latest pass of the start point before reaching the end, e.g. the athlete passed
+		// the start in the opposite direction, turned around and then passed it again.
+		for {
+			_, nextStart, afterNextStart := closestWithin(afterStart, 0)
+			if nextStart == -1 || afterNextStart > endFirst {
+				break
+			}
+			start, afterStart = nextStart, afterNextStart
+		}
+		cur = afterStart
+
+		if !followsPath(records, start, end, segment, distanceTo) {
+			continue
+		}
+
+		effort := newSegmentEffort(records, start, end)
+		if effort.Distance > maxDistance {
+			continue
+		}
+
+		efforts = append(efforts, effort)
+		cur = end // The end may be the start of the next pass, e.g. a loop.
+	}
+
+	return efforts
+}
+
+// followsPath reports whether records between start and end pass segment's intermediate points in order.
+func followsPath(records []Record, start, end int, segment *Segment, distanceTo func(i, point int) float64) bool {
+	radius := segment.radius()
+	cur := start
+	for point := 1; point < len(segment.Lats)-1; point++ {
+		for ; cur <= end; cur++ {
+			if distanceTo(cur, point) <= radius {
+				break
+			}
+		}
+		if cur > end {
+			return false
+		}
+	}
+	return true
+}
+
+func newSegmentEffort(records []Record, start, end int) SegmentEffort {
+	effort := SegmentEffort{
+		StartIndex:   start,
+		EndIndex:     end,
+		StartTime:    records[start].Timestamp,
+		AvgPower:     math.NaN(),
+		AvgHeartRate: math.NaN(),
+	}
+	if !records[start].Timestamp.IsZero() && !records[end].Timestamp.IsZero() {
+		effort.ElapsedTime = records[end].Timestamp.Sub(records[start].Timestamp)
+	}
+
+	if records[start].Distance != basetype.Uint32Invalid && records[end].Distance != basetype.Uint32Invalid &&
+		records[end].Distance >= records[start].Distance {
+		effort.Distance = records[end].DistanceScaled() - records[start].DistanceScaled()
+	} else {
+		prevLat, prevLong, hasPrev := records[start].positionDegrees()
+		for i := start + 1; i <= end; i++ {
+			lat, long, ok := records[i].positionDegrees()
+			if !ok {
+				continue
+			}
+			if hasPrev {
+				effort.Distance += geomath.HaversineDistance(prevLat, prevLong, lat, long)
+			}
+			prevLat, prevLong, hasPrev = lat, long, true
+		}
+	}
+
+	var power, heartRate float64
+	var powerCount, heartRateCount int
+	for i := start; i <= end; i++ {
+		if records[i].Power != basetype.Uint16Invalid {
+			power += float64(records[i].Power)
+			powerCount++
+		}
+		if records[i].HeartRate != basetype.Uint8Invalid {
+			heartRate += float64(records[i].HeartRate)
+			heartRateCount++
+		}
+	}
+	if powerCount > 0 {
+		effort.AvgPower = power / float64(powerCount)
+	}
+	if heartRateCount > 0 {
+		effort.AvgHeartRate = heartRate / float64(heartRateCount)
+	}
+
+	return effort
+}
+
+// MarshalAppendJSON appends the JSON format encoding of SegmentEffort to b, returning the result.
+func (s *SegmentEffort) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"startIndex":`...)
+	b = strconv.AppendInt(b, int64(s.StartIndex), 10)
+	b = append(b, `,"endIndex":`...)
+	b = strconv.AppendInt(b, int64(s.EndIndex), 10)
+	if !s.StartTime.IsZero() {
+		b = append(b, `,"startTime":`...)
+		b = strconv.AppendQuote(b, s.StartTime.Format(time.RFC3339))
+	}
+	b = append(b, `,"elapsedTime":`...)
+	b = strconv.AppendFloat(b, s.ElapsedTime.Seconds(), 'g', -1, 64)
+	b = append(b, `,"distance":`...)
+	b = strconv.AppendFloat(b, s.Distance, 'f', 1, 64)
+	if !math.IsNaN(s.AvgPower) {
+		b = append(b, `,"avgPower":`...)
+		b = strconv.AppendFloat(b, s.AvgPower, 'f', 0, 64)
+	}
+	if !math.IsNaN(s.AvgHeartRate) {
+		b = append(b, `,"avgHeartRate":`...)
+		b = strconv.AppendFloat(b, s.AvgHeartRate, 'f', 0, 64)
+	}
+	return append(b, '}')
+}
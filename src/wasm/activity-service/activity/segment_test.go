@@ -0,0 +1,107 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/kit/semicircles"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/openivity/activity-service/activity"
+)
+
+// newTrack creates records every 10 seconds along the equator from long 0 to 0.01 degree (~1.1km) with
+// 0.0005 degree (~56m) apart, repeated by the given laps.
+func newTrack(laps int) []activity.Record {
+	const n = 21
+	offsets := make([]int, n*laps)
+	for i := range offsets {
+		offsets[i] = i * 10
+	}
+	return newRecords(offsets, func(i int, rec *mesgdef.Record) {
+		rec.SetPositionLat(0).SetPositionLong(semicircles.ToSemicircles(float64(i%n) * 0.0005))
+	})
+}
+
+func TestMatchSegment(t *testing.T) {
+	tt := []struct {
+		name    string
+		records []activity.Record
+		segment activity.Segment
+		efforts [][2]int // start and end index
+	}{
+		{
+			name:    "single pass",
+			records: newTrack(1),
+			segment: activity.Segment{Lats: []float64{0, 0, 0}, Longs: []float64{0.002, 0.004, 0.006}},
+			efforts: [][2]int{{4, 12}},
+		},
+		{
+			name:    "two passes",
+			records: newTrack(2),
+			segment: activity.Segment{Lats: []float64{0, 0}, Longs: []float64{0.002, 0.006}},
+			efforts: [][2]int{{4, 12}, {25, 33}},
+		},
+		{
+			name:    "opposite direction",
+			records: newTrack(1),
+			segment: activity.Segment{Lats: []float64{0, 0}, Longs: []float64{0.006, 0.002}},
+		},
+		{
+			name:    "off the path",
+			records: newTrack(1),
+			segment: activity.Segment{Lats: []float64{0, 0.001, 0}, Longs: []float64{0.002, 0.004, 0.006}},
+		},
+		{
+			name:    "outside default radius",
+			records: newTrack(1),
+			segment: activity.Segment{Lats: []float64{0.0003, 0.0003}, Longs: []float64{0.002, 0.006}}, // ~33m away
+		},
+		{
+			name:    "within segment's radius",
+			records: newTrack(1),
+			segment: activity.Segment{Lats: []float64{0.0003, 0.0003}, Longs: []float64{0.002, 0.006}, Radius: 40},
+			efforts: [][2]int{{4, 12}},
+		},
+		{
+			name:    "invalid segment",
+			records: newTrack(1),
+			segment: activity.Segment{Lats: []float64{0}, Longs: []float64{0.002}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			efforts := activity.MatchSegment(tc.records, &tc.segment)
+			if len(efforts) != len(tc.efforts) {
+				t.Fatalf("expected len: %d, got: %d", len(tc.efforts), len(efforts))
+			}
+			for i := range efforts {
+				e := &efforts[i]
+				if e.StartIndex != tc.efforts[i][0] || e.EndIndex != tc.efforts[i][1] {
+					t.Fatalf("[%d]: expected: %v, got: [%d %d]", i, tc.efforts[i], e.StartIndex, e.EndIndex)
+				}
+				if expected := time.Duration(e.EndIndex-e.StartIndex) * 10 * time.Second; e.ElapsedTime != expected {
+					t.Fatalf("[%d]: expected elapsed time: %s, got: %s", i, expected, e.ElapsedTime)
+				}
+				if e.Distance < 440 || e.Distance > 450 { // ~8 * 55.6m
+					t.Fatalf("[%d]: expected distance ~445m, got: %g", i, e.Distance)
+				}
+			}
+		})
+	}
+}
@@ -33,6 +33,20 @@ type Session struct {
 
 	Laps    []Lap
 	Records []Record
+	Lengths []Length // Only for pool swimming.
+
+	FixedPositions       int     // Number of GPS outliers fixed by the preprocessor.
+	ElevationGainMethod  string  // Method used to calculate TotalAscent and TotalDescent, see ElevationGain.
+	VariabilityIndex     float64 // NormalizedPower / AvgPower, zero if not calculated.
+	AvgGradeAdjustedPace float64 // in seconds per km, zero if not calculated.
+	AvgSWOLF             float64 // Average SWOLF of active lengths, zero if not calculated.
+	EstimatedCalories    float64 // in kcal, estimated when TotalCalories is not recorded, zero if not estimated.
+
+	TimeInHeartRateZone TimeInZone
+	Splits              []Split
+	Climbs              []Climb
+	Stops               []Stop
+	Efficiency          *Efficiency // nil if not calculated.
 }
 
 // CreateSession creates new session.
@@ -102,8 +116,80 @@ func (s *Session) PutRecords(records ...Record) (remainings []Record) {
 	return records[pos:]
 }
 
+// PutLengths puts given lengths into session and return any remaining lengths that doesn't belong to this session.
+// Lengths' pool length is taken from the session's pool length if not specified.
+func (s *Session) PutLengths(lengths ...Length) (remainings []Length) {
+	var pos int
+	for i := range lengths {
+		if s.IsBelongToThisSession(lengths[i].StartTime) {
+			lengths[i], lengths[pos] = lengths[pos], lengths[i]
+			pos++
+		}
+	}
+	for i := range lengths[:pos] {
+		if math.IsNaN(lengths[i].PoolLength) && s.PoolLength != basetype.Uint16Invalid {
+			lengths[i].PoolLength = s.PoolLengthScaled()
+		}
+	}
+	s.Lengths = append(s.Lengths, lengths[:pos]...)
+	return lengths[pos:]
+}
+
+// TrimLengths removes lengths that are not within the given time window, e.g. after the records are trimmed.
+func (s *Session) TrimLengths(startTime, endTime time.Time) {
+	lengths := s.Lengths[:0]
+	for i := range s.Lengths {
+		if isBelong(s.Lengths[i].StartTime, startTime, endTime) {
+			lengths = append(lengths, s.Lengths[i])
+		}
+	}
+	s.Lengths = lengths
+}
+
+// SummarizeLengths renumbers lengths' MessageIndex starting from firstIndex and updates laps' and session's
+// length references (FirstLengthIndex, NumLengths and NumActiveLengths) so they stay consistent after lengths
+// are trimmed or combined. It returns the next message index for the subsequent session.
+func (s *Session) SummarizeLengths(firstIndex uint16) uint16 {
+	if len(s.Lengths) == 0 {
+		return firstIndex
+	}
+
+	for i := range s.Lengths {
+		s.Lengths[i].MessageIndex = typedef.MessageIndex(firstIndex + uint16(i))
+	}
+
+	var numActive uint16
+	for i := range s.Lengths {
+		if s.Lengths[i].IsActive() {
+			numActive++
+		}
+	}
+	s.NumLengths = uint16(len(s.Lengths))
+	s.NumActiveLengths = numActive
+
+	for i := range s.Laps {
+		lap := &s.Laps[i]
+		lap.FirstLengthIndex, lap.NumLengths, lap.NumActiveLengths = basetype.Uint16Invalid, 0, 0
+		for j := range s.Lengths {
+			length := &s.Lengths[j]
+			if !lap.IsBelongToThisLap(length.StartTime) {
+				continue
+			}
+			if lap.FirstLengthIndex == basetype.Uint16Invalid {
+				lap.FirstLengthIndex = uint16(length.MessageIndex)
+			}
+			lap.NumLengths++
+			if length.IsActive() {
+				lap.NumActiveLengths++
+			}
+		}
+	}
+
+	return firstIndex + uint16(len(s.Lengths))
+}
+
 // Summarize summarizes the session such as updating StartPosition and EndPosition based on records.
-func (s *Session) Summarize() {
+func (s *Session) Summarize(sm Summarizer) {
 	// Update GPS Positions
 	for i := range s.Records {
 		rec := &s.Records[i]
@@ -130,16 +216,24 @@ func (s *Session) Summarize() {
 	}
 
 	if s.TotalMovingTime == basetype.Uint32Invalid {
-		s.TotalMovingTime = TotalMovingTime(s.Records, s.Sport)
+		s.TotalMovingTime = sm.TotalMovingTime(s.Records, s.Sport)
 	}
 
 	if s.TotalAscent == basetype.Uint16Invalid || s.TotalDescent == basetype.Uint16Invalid {
-		s.TotalAscent, s.TotalDescent = TotalAscentAndDescent(s.Records)
+		s.TotalAscent, s.TotalDescent = sm.TotalAscentAndDescent(s.Records)
+	}
+	if s.ElevationGainMethod == "" && s.TotalAscent != basetype.Uint16Invalid {
+		s.ElevationGainMethod = sm.ElevationGainAlgorithm().Name()
 	}
 
 	if s.AvgSpeed == basetype.Uint16Invalid || s.MaxSpeed == basetype.Uint16Invalid {
 		s.AvgSpeed, s.MaxSpeed = AvgMaxSpeed(s.Records)
 	}
+
+	s.AvgSWOLF = 0
+	if swolf := AvgSWOLF(s.Lengths); !math.IsNaN(swolf) {
+		s.AvgSWOLF = swolf
+	}
 }
 
 // MarshalAppendJSON appends the JSON format encoding of Session to b, returning the result.
@@ -200,6 +294,11 @@ func (s *Session) MarshalAppendJSON(b []byte) []byte {
 		b = strconv.AppendUint(b, uint64(s.TotalCalories), 10)
 		b = append(b, ',')
 	}
+	if s.EstimatedCalories > 0 {
+		b = append(b, `"estimatedCalories":`...)
+		b = strconv.AppendFloat(b, s.EstimatedCalories, 'f', 0, 64)
+		b = append(b, ',')
+	}
 
 	avgSpeed := s.AvgSpeedScaled()
 	if math.IsNaN(avgSpeed) {
@@ -251,6 +350,31 @@ func (s *Session) MarshalAppendJSON(b []byte) []byte {
 		b = strconv.AppendUint(b, uint64(s.MaxPower), 10)
 		b = append(b, ',')
 	}
+	if s.NormalizedPower != basetype.Uint16Invalid {
+		b = append(b, `"normalizedPower":`...)
+		b = strconv.AppendUint(b, uint64(s.NormalizedPower), 10)
+		b = append(b, ',')
+	}
+	if s.IntensityFactor != basetype.Uint16Invalid {
+		b = append(b, `"intensityFactor":`...)
+		b = strconv.AppendFloat(b, s.IntensityFactorScaled(), 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if s.TrainingStressScore != basetype.Uint16Invalid {
+		b = append(b, `"trainingStressScore":`...)
+		b = strconv.AppendFloat(b, s.TrainingStressScoreScaled(), 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if s.VariabilityIndex != 0 {
+		b = append(b, `"variabilityIndex":`...)
+		b = strconv.AppendFloat(b, s.VariabilityIndex, 'g', 4, 64)
+		b = append(b, ',')
+	}
+	if s.TotalWork != basetype.Uint32Invalid {
+		b = append(b, `"totalWork":`...)
+		b = strconv.AppendUint(b, uint64(s.TotalWork), 10)
+		b = append(b, ',')
+	}
 	if s.AvgTemperature != basetype.Sint8Invalid {
 		b = append(b, `"avgTemperature":`...)
 		b = strconv.AppendInt(b, int64(s.AvgTemperature), 10)
@@ -296,6 +420,90 @@ func (s *Session) MarshalAppendJSON(b []byte) []byte {
 			b = append(b, ',')
 		}
 	}
+	if s.AvgGradeAdjustedPace != 0 {
+		b = append(b, `"avgGradeAdjustedPace":`...)
+		b = strconv.AppendFloat(b, s.AvgGradeAdjustedPace, 'g', -1, 64)
+		b = append(b, ',')
+	}
+	if s.AvgSWOLF != 0 {
+		b = append(b, `"avgSwolf":`...)
+		b = strconv.AppendFloat(b, s.AvgSWOLF, 'f', 1, 64)
+		b = append(b, ',')
+	}
+	if s.PoolLength != basetype.Uint16Invalid {
+		b = append(b, `"poolLength":`...)
+		b = strconv.AppendFloat(b, s.PoolLengthScaled(), 'g', -1, 64)
+		b = append(b, ',')
+	}
+
+	if s.ElevationGainMethod != "" {
+		b = append(b, `"elevationGainMethod":`...)
+		b = strconv.AppendQuote(b, s.ElevationGainMethod)
+		b = append(b, ',')
+	}
+	if s.FixedPositions != 0 {
+		b = append(b, `"fixedPositions":`...)
+		b = strconv.AppendInt(b, int64(s.FixedPositions), 10)
+		b = append(b, ',')
+	}
+	if s.TimeInHeartRateZone.IsValid() {
+		b = append(b, `"timeInHeartRateZone":`...)
+		b = s.TimeInHeartRateZone.MarshalAppendJSON(b)
+		b = append(b, ',')
+	}
+
+	if s.Efficiency != nil {
+		b = append(b, `"efficiency":`...)
+		b = s.Efficiency.MarshalAppendJSON(b)
+		b = append(b, ',')
+	}
+	if len(s.Splits) != 0 {
+		b = append(b, `"splits":[`...)
+		for i := range s.Splits {
+			b = s.Splits[i].MarshalAppendJSON(b)
+			if i != len(s.Splits)-1 {
+				b = append(b, ',')
+			}
+		}
+		b = append(b, ']')
+		b = append(b, ',')
+	}
+
+	if len(s.Climbs) != 0 {
+		b = append(b, `"climbs":[`...)
+		for i := range s.Climbs {
+			b = s.Climbs[i].MarshalAppendJSON(b)
+			if i != len(s.Climbs)-1 {
+				b = append(b, ',')
+			}
+		}
+		b = append(b, ']')
+		b = append(b, ',')
+	}
+
+	if len(s.Lengths) != 0 {
+		b = append(b, `"lengths":[`...)
+		for i := range s.Lengths {
+			b = s.Lengths[i].MarshalAppendJSON(b)
+			if i != len(s.Lengths)-1 {
+				b = append(b, ',')
+			}
+		}
+		b = append(b, ']')
+		b = append(b, ',')
+	}
+
+	if len(s.Stops) != 0 {
+		b = append(b, `"stops":[`...)
+		for i := range s.Stops {
+			b = s.Stops[i].MarshalAppendJSON(b)
+			if i != len(s.Stops)-1 {
+				b = append(b, ',')
+			}
+		}
+		b = append(b, ']')
+		b = append(b, ',')
+	}
 
 	b = append(b, `"laps":[`...)
 	for i := range s.Laps {
@@ -0,0 +1,186 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/openivity/activity-service/geomath"
+)
+
+// SimplifyRecords removes records whose positions are not significant to the shape of the track using
+// Douglas-Peucker algorithm with the given tolerance in meters. Records without position are kept as it is,
+// so the first and the last records are always retained as well as the session's time window.
+func SimplifyRecords(records []Record, tolerance float64) []Record {
+	if tolerance <= 0 || len(records) < 3 {
+		return records
+	}
+
+	indexes := make([]int, 0, len(records))
+	lats := make([]float64, 0, len(records))
+	lons := make([]float64, 0, len(records))
+	for i := range records {
+		rec := &records[i]
+		if rec.PositionLat == basetype.Sint32Invalid || rec.PositionLong == basetype.Sint32Invalid {
+			continue
+		}
+		indexes = append(indexes, i)
+		lats = append(lats, rec.PositionLatDegrees())
+		lons = append(lons, rec.PositionLongDegrees())
+	}
+
+	keep := make([]bool, len(records))
+	for i := range records {
+		rec := &records[i]
+		if rec.PositionLat == basetype.Sint32Invalid || rec.PositionLong == basetype.Sint32Invalid {
+			keep[i] = true
+		}
+	}
+	for _, n := range geomath.DouglasPeucker(lats, lons, tolerance) {
+		keep[indexes[n]] = true
+	}
+	keep[0], keep[len(keep)-1] = true, true
+
+	simplified := make([]Record, 0, len(indexes))
+	for i := range records {
+		if keep[i] {
+			simplified = append(simplified, records[i])
+		}
+	}
+	return simplified
+}
+
+// ResampleRecords resamples records into 1 record per given interval. Sensor values (altitude, cadence, speed,
+// heart rate, power and temperature) within the same interval are averaged, while the timestamp, position and
+// distance are taken from the first record having those values. The last record is always retained.
+func ResampleRecords(records []Record, interval time.Duration) []Record {
+	if interval <= 0 || len(records) < 2 {
+		return records
+	}
+
+	resampled := make([]Record, 0)
+	for i := 0; i < len(records); {
+		rec := records[i]
+		if rec.Timestamp.IsZero() {
+			resampled = append(resampled, rec)
+			i++
+			continue
+		}
+
+		end := rec.Timestamp.Add(interval)
+		j := i + 1
+		for ; j < len(records)-1; j++ { // Exclude the last record, we retain it as it is.
+			if !records[j].Timestamp.IsZero() && !records[j].Timestamp.Before(end) {
+				break
+			}
+		}
+
+		resampled = append(resampled, aggregateRecords(records[i:j]))
+		i = j
+	}
+
+	return resampled
+}
+
+// aggregateRecords aggregates records into the first record.
+func aggregateRecords(records []Record) Record {
+	rec := records[0]
+	if len(records) == 1 {
+		return rec
+	}
+
+	var (
+		altitude, altitudeCount                 uint64
+		enhancedAltitude, enhancedAltitudeCount uint64
+		cadence, cadenceCount                   uint64
+		speed, speedCount                       uint64
+		enhancedSpeed, enhancedSpeedCount       uint64
+		heartRate, heartRateCount               uint64
+		power, powerCount                       uint64
+		temperature, temperatureCount           int64
+	)
+
+	for i := range records {
+		r := &records[i]
+
+		if rec.PositionLat == basetype.Sint32Invalid || rec.PositionLong == basetype.Sint32Invalid {
+			rec.PositionLat, rec.PositionLong = r.PositionLat, r.PositionLong
+		}
+		if rec.Distance == basetype.Uint32Invalid {
+			rec.Distance = r.Distance
+		}
+		if r.Altitude != basetype.Uint16Invalid {
+			altitude += uint64(r.Altitude)
+			altitudeCount++
+		}
+		if r.EnhancedAltitude != basetype.Uint32Invalid {
+			enhancedAltitude += uint64(r.EnhancedAltitude)
+			enhancedAltitudeCount++
+		}
+		if r.Cadence != basetype.Uint8Invalid {
+			cadence += uint64(r.Cadence)
+			cadenceCount++
+		}
+		if r.Speed != basetype.Uint16Invalid {
+			speed += uint64(r.Speed)
+			speedCount++
+		}
+		if r.EnhancedSpeed != basetype.Uint32Invalid {
+			enhancedSpeed += uint64(r.EnhancedSpeed)
+			enhancedSpeedCount++
+		}
+		if r.HeartRate != basetype.Uint8Invalid {
+			heartRate += uint64(r.HeartRate)
+			heartRateCount++
+		}
+		if r.Power != basetype.Uint16Invalid {
+			power += uint64(r.Power)
+			powerCount++
+		}
+		if r.Temperature != basetype.Sint8Invalid {
+			temperature += int64(r.Temperature)
+			temperatureCount++
+		}
+	}
+
+	if altitudeCount != 0 {
+		rec.Altitude = uint16(altitude / altitudeCount)
+	}
+	if enhancedAltitudeCount != 0 {
+		rec.EnhancedAltitude = uint32(enhancedAltitude / enhancedAltitudeCount)
+	}
+	if cadenceCount != 0 {
+		rec.Cadence = uint8(cadence / cadenceCount)
+	}
+	if speedCount != 0 {
+		rec.Speed = uint16(speed / speedCount)
+	}
+	if enhancedSpeedCount != 0 {
+		rec.EnhancedSpeed = uint32(enhancedSpeed / enhancedSpeedCount)
+	}
+	if heartRateCount != 0 {
+		rec.HeartRate = uint8(heartRate / heartRateCount)
+	}
+	if powerCount != 0 {
+		rec.Power = uint16(power / powerCount)
+	}
+	if temperatureCount != 0 {
+		rec.Temperature = int8(temperature / temperatureCount)
+	}
+
+	return rec
+}
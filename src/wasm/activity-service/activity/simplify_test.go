@@ -0,0 +1,152 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/openivity/activity-service/activity"
+)
+
+func TestSimplifyRecords(t *testing.T) {
+	tt := []struct {
+		name      string
+		lats      []float64 // 0 means no position.
+		lons      []float64
+		tolerance float64
+		expected  []int // expected offsets retained.
+	}{
+		{
+			name:      "points on a straight line are removed",
+			lats:      []float64{0.001, 0.002, 0.003, 0.004, 0.005},
+			lons:      []float64{10, 10, 10, 10, 10},
+			tolerance: 1,
+			expected:  []int{0, 4},
+		},
+		{
+			name:      "corner is retained",
+			lats:      []float64{0.001, 0.002, 0.003, 0.003, 0.003},
+			lons:      []float64{10, 10, 10, 10.001, 10.002},
+			tolerance: 1,
+			expected:  []int{0, 2, 4},
+		},
+		{
+			name:      "records without position are retained",
+			lats:      []float64{0.001, 0.002, 0, 0.004, 0.005},
+			lons:      []float64{10, 10, 0, 10, 10},
+			tolerance: 1,
+			expected:  []int{0, 2, 4},
+		},
+		{
+			name:      "zero tolerance retains all",
+			lats:      []float64{0.001, 0.002, 0.003},
+			lons:      []float64{10, 10, 10},
+			tolerance: 0,
+			expected:  []int{0, 1, 2},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			offsets := make([]int, len(tc.lats))
+			for i := range offsets {
+				offsets[i] = i
+			}
+			records := newRecords(offsets, func(i int, rec *mesgdef.Record) {
+				if tc.lats[i] != 0 {
+					rec.SetPositionLatDegrees(tc.lats[i]).SetPositionLongDegrees(tc.lons[i])
+				}
+			})
+
+			simplified := activity.SimplifyRecords(records, tc.tolerance)
+			if len(simplified) != len(tc.expected) {
+				t.Fatalf("expected: %d records, got: %d", len(tc.expected), len(simplified))
+			}
+			for i := range simplified {
+				expected := t0.Add(time.Duration(tc.expected[i]) * time.Second)
+				if !simplified[i].Timestamp.Equal(expected) {
+					t.Fatalf("[%d] expected: %s, got: %s", i, expected, simplified[i].Timestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestResampleRecords(t *testing.T) {
+	tt := []struct {
+		name       string
+		offsets    []int
+		heartRates []uint8
+		interval   time.Duration
+		expected   []int // expected offsets retained.
+		expectedHR []uint8
+	}{
+		{
+			name:       "values within an interval are averaged",
+			offsets:    []int{0, 1, 2, 3, 4, 5},
+			heartRates: []uint8{100, 110, 120, 130, 140, 150},
+			interval:   2 * time.Second,
+			expected:   []int{0, 2, 4, 5},
+			expectedHR: []uint8{105, 125, 140, 150},
+		},
+		{
+			name:       "last record is retained as it is",
+			offsets:    []int{0, 1, 2, 3},
+			heartRates: []uint8{100, 110, 120, 130},
+			interval:   10 * time.Second,
+			expected:   []int{0, 3},
+			expectedHR: []uint8{110, 130},
+		},
+		{
+			name:       "interval wider than the gap",
+			offsets:    []int{0, 10, 20},
+			heartRates: []uint8{100, 110, 120},
+			interval:   5 * time.Second,
+			expected:   []int{0, 10, 20},
+			expectedHR: []uint8{100, 110, 120},
+		},
+		{
+			name:       "zero interval retains all",
+			offsets:    []int{0, 1, 2},
+			heartRates: []uint8{100, 110, 120},
+			interval:   0,
+			expected:   []int{0, 1, 2},
+			expectedHR: []uint8{100, 110, 120},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			records := newRecords(tc.offsets, func(i int, rec *mesgdef.Record) { rec.SetHeartRate(tc.heartRates[i]) })
+
+			resampled := activity.ResampleRecords(records, tc.interval)
+			if len(resampled) != len(tc.expected) {
+				t.Fatalf("expected: %d records, got: %d", len(tc.expected), len(resampled))
+			}
+			for i := range resampled {
+				expected := t0.Add(time.Duration(tc.expected[i]) * time.Second)
+				if !resampled[i].Timestamp.Equal(expected) {
+					t.Fatalf("[%d] expected: %s, got: %s", i, expected, resampled[i].Timestamp)
+				}
+				if resampled[i].HeartRate != tc.expectedHR[i] {
+					t.Fatalf("[%d] expected heart rate: %d, got: %d", i, tc.expectedHR[i], resampled[i].HeartRate)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// Split lengths in meters.
+const (
+	SplitLengthKilometer = 1000
+	SplitLengthMile      = 1609.344
+)
+
+// minSplitDistance is the minimum distance of the last partial split, anything shorter is discarded.
+const minSplitDistance = 10 // meters
+
+// Split is a distance-based split of a session.
+type Split struct {
+	StartTime         time.Time
+	EndTime           time.Time
+	Distance          float64       // in meters, the last split may be shorter than the split length.
+	ElapsedTime       time.Duration // Interpolated at the split boundaries.
+	MovingTime        time.Duration
+	Pace              float64 // in seconds per km, based on moving time if any, otherwise elapsed time.
+	GradeAdjustedPace float64 // in seconds per km, NaN if the sport has no pace.
+	Ascent            float64 // in meters
+	Descent           float64 // in meters
+	AvgHeartRate      float64 // NaN if there is no heart rate.
+	AvgPower          float64 // NaN if there is no power.
+	StartPositionLat  int32
+	StartPositionLong int32
+	EndPositionLat    int32
+	EndPositionLong   int32
+}
+
+// NewSplits splits records into distance-based splits with the given length in meters starting from the first
+// record having distance, the split boundaries' time are interpolated between the two records surrounding them.
+func NewSplits(records []Record, sport typedef.Sport, length float64, sm Summarizer) []Split {
+	if length <= 0 {
+		return nil
+	}
+
+	var (
+		splits        []Split
+		splitStart    = -1 // index of the first record of current split
+		prev          = -1 // index of the previous valid record
+		startDistance float64
+		startTime     time.Time
+		boundary      float64
+		isValid       = func(rec *Record) bool {
+			return rec.Distance != basetype.Uint32Invalid && !rec.Timestamp.IsZero()
+		}
+	)
+
+	for i := range records {
+		rec := &records[i]
+		if !isValid(rec) {
+			continue
+		}
+		if splitStart == -1 {
+			splitStart, prev = i, i
+			startDistance, startTime = rec.DistanceScaled(), rec.Timestamp
+			boundary = startDistance + length
+			continue
+		}
+
+		distance := rec.DistanceScaled()
+		for distance >= boundary {
+			p := &records[prev]
+			ratio := (boundary - p.DistanceScaled()) / (distance - p.DistanceScaled())
+			endTime := p.Timestamp.Add(time.Duration(float64(rec.Timestamp.Sub(p.Timestamp)) * ratio))
+
+			splits = append(splits, newSplit(records[splitStart:i+1], sport, length, startTime, endTime, sm))
+
+			splitStart = prev
+			startDistance, startTime = boundary, endTime
+			boundary += length
+		}
+		prev = i
+	}
+
+	if prev != -1 {
+		last := &records[prev]
+		if distance := last.DistanceScaled() - startDistance; distance >= minSplitDistance {
+			splits = append(splits, newSplit(records[splitStart:prev+1], sport, distance, startTime, last.Timestamp, sm))
+		}
+	}
+
+	return splits
+}
+
+// newSplit creates new split from the records covering the split.
+func newSplit(records []Record, sport typedef.Sport, distance float64, startTime, endTime time.Time, sm Summarizer) Split {
+	split := Split{
+		StartTime:         startTime,
+		EndTime:           endTime,
+		Distance:          distance,
+		ElapsedTime:       endTime.Sub(startTime),
+		GradeAdjustedPace: math.NaN(),
+		AvgHeartRate:      math.NaN(),
+		AvgPower:          math.NaN(),
+		StartPositionLat:  basetype.Sint32Invalid,
+		StartPositionLong: basetype.Sint32Invalid,
+		EndPositionLat:    basetype.Sint32Invalid,
+		EndPositionLong:   basetype.Sint32Invalid,
+	}
+
+	if movingTime := sm.TotalMovingTime(records, sport); movingTime != basetype.Uint32Invalid {
+		split.MovingTime = min(time.Duration(movingTime)*time.Millisecond, split.ElapsedTime)
+	}
+
+	duration := split.MovingTime
+	if duration == 0 {
+		duration = split.ElapsedTime
+	}
+	split.Pace = duration.Seconds() / (distance / 1000)
+
+	if ascent, descent, ok := sm.ElevationGainAlgorithm().AscentAndDescent(records); ok {
+		split.Ascent, split.Descent = ascent, descent
+	}
+
+	var heartRate, heartRateCount, power, powerCount float64
+	var rawDistance, adjustedDistance float64
+	for i := range records {
+		rec := &records[i]
+		if rec.HeartRate != basetype.Uint8Invalid {
+			heartRate += float64(rec.HeartRate)
+			heartRateCount++
+		}
+		if rec.Power != basetype.Uint16Invalid {
+			power += float64(rec.Power)
+			powerCount++
+		}
+		if rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid {
+			if split.StartPositionLat == basetype.Sint32Invalid {
+				split.StartPositionLat, split.StartPositionLong = rec.PositionLat, rec.PositionLong
+			}
+			split.EndPositionLat, split.EndPositionLong = rec.PositionLat, rec.PositionLong
+		}
+		if i > 0 && rec.Distance != basetype.Uint32Invalid && records[i-1].Distance != basetype.Uint32Invalid {
+			d := rec.DistanceScaled() - records[i-1].DistanceScaled()
+			rawDistance += d
+			adjustedDistance += d * GradeAdjustedFactor(rec.Grade)
+		}
+	}
+	if heartRateCount != 0 {
+		split.AvgHeartRate = heartRate / heartRateCount
+	}
+	if powerCount != 0 {
+		split.AvgPower = power / powerCount
+	}
+	if HasPace(sport) && rawDistance > 0 && adjustedDistance > 0 {
+		split.GradeAdjustedPace = split.Pace * rawDistance / adjustedDistance
+	}
+
+	return split
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Split to b, returning the result.
+func (s *Split) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '{')
+
+	b = append(b, `"startTime":`...)
+	b = strconv.AppendQuote(b, s.StartTime.Format(time.RFC3339))
+	b = append(b, ',')
+
+	b = append(b, `"distance":`...)
+	b = strconv.AppendFloat(b, s.Distance, 'f', 2, 64)
+	b = append(b, ',')
+
+	b = append(b, `"elapsedTime":`...)
+	b = strconv.AppendFloat(b, s.ElapsedTime.Seconds(), 'f', 1, 64)
+	b = append(b, ',')
+
+	b = append(b, `"movingTime":`...)
+	b = strconv.AppendFloat(b, s.MovingTime.Seconds(), 'f', 1, 64)
+	b = append(b, ',')
+
+	b = append(b, `"pace":`...)
+	b = strconv.AppendFloat(b, s.Pace, 'f', 1, 64)
+	b = append(b, ',')
+
+	if !math.IsNaN(s.GradeAdjustedPace) {
+		b = append(b, `"gradeAdjustedPace":`...)
+		b = strconv.AppendFloat(b, s.GradeAdjustedPace, 'f', 1, 64)
+		b = append(b, ',')
+	}
+
+	b = append(b, `"ascent":`...)
+	b = strconv.AppendFloat(b, s.Ascent, 'f', 1, 64)
+	b = append(b, ',')
+
+	b = append(b, `"descent":`...)
+	b = strconv.AppendFloat(b, s.Descent, 'f', 1, 64)
+	b = append(b, ',')
+
+	if !math.IsNaN(s.AvgHeartRate) {
+		b = append(b, `"avgHeartRate":`...)
+		b = strconv.AppendFloat(b, s.AvgHeartRate, 'f', 0, 64)
+		b = append(b, ',')
+	}
+	if !math.IsNaN(s.AvgPower) {
+		b = append(b, `"avgPower":`...)
+		b = strconv.AppendFloat(b, s.AvgPower, 'f', 0, 64)
+		b = append(b, ',')
+	}
+
+	if b[len(b)-1] == ',' {
+		b = b[:len(b)-1]
+	}
+
+	return append(b, '}')
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/openivity/activity-service/activity"
+)
+
+func TestNewSplits(t *testing.T) {
+	sm := activity.NewPreprocessor().Summarizer()
+
+	type split struct {
+		distance    float64
+		elapsedTime time.Duration
+	}
+
+	tt := []struct {
+		name     string
+		total    int // duration in seconds at 4 m/s, a record every 10s.
+		length   float64
+		expected []split
+	}{
+		{
+			name:   "last partial split",
+			total:  650,
+			length: activity.SplitLengthKilometer,
+			expected: []split{
+				{distance: 1000, elapsedTime: 250 * time.Second},
+				{distance: 1000, elapsedTime: 250 * time.Second},
+				{distance: 600, elapsedTime: 150 * time.Second},
+			},
+		},
+		{
+			name:   "boundary is interpolated between records",
+			total:  420,
+			length: activity.SplitLengthMile,
+			expected: []split{
+				{distance: activity.SplitLengthMile, elapsedTime: time.Duration(activity.SplitLengthMile / 4 * float64(time.Second))},
+				{distance: 1680 - activity.SplitLengthMile, elapsedTime: time.Duration((1680 - activity.SplitLengthMile) / 4 * float64(time.Second))},
+			},
+		},
+		{
+			name:   "last partial split shorter than 10m is discarded",
+			total:  500,
+			length: activity.SplitLengthKilometer,
+			expected: []split{
+				{distance: 1000, elapsedTime: 250 * time.Second},
+				{distance: 1000, elapsedTime: 250 * time.Second},
+			},
+		},
+		{
+			name:   "zero length",
+			total:  500,
+			length: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var offsets []int
+			for offset := 0; offset <= tc.total; offset += 10 {
+				offsets = append(offsets, offset)
+			}
+			records := newRecords(offsets, func(i int, rec *mesgdef.Record) {
+				rec.SetDistanceScaled(float64(offsets[i]) * 4).SetSpeedScaled(4).SetHeartRate(150)
+			})
+
+			splits := activity.NewSplits(records, typedef.SportRunning, tc.length, sm)
+			if len(splits) != len(tc.expected) {
+				t.Fatalf("expected: %d splits, got: %d", len(tc.expected), len(splits))
+			}
+			for i := range splits {
+				s := &splits[i]
+				if !equalFloat(s.Distance, tc.expected[i].distance) {
+					t.Fatalf("[%d] expected distance: %g, got: %g", i, tc.expected[i].distance, s.Distance)
+				}
+				if s.ElapsedTime.Round(time.Millisecond) != tc.expected[i].elapsedTime.Round(time.Millisecond) {
+					t.Fatalf("[%d] expected elapsed time: %s, got: %s", i, tc.expected[i].elapsedTime, s.ElapsedTime)
+				}
+				if s.AvgHeartRate != 150 {
+					t.Fatalf("[%d] expected avg heart rate: 150, got: %g", i, s.AvgHeartRate)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/typedef"
+)
+
+// DefaultMinStopDuration is the default minimum duration of a pause to be considered as a stop.
+const DefaultMinStopDuration = time.Minute
+
+// Stop is a pause that lasts at least a minimum duration, e.g. a traffic light wait is not a stop but a coffee break is.
+type Stop struct {
+	Pause
+	PositionLat  float64 // Latitude in degrees where the athlete stopped, NaN if unknown.
+	PositionLong float64 // Longitude in degrees where the athlete stopped, NaN if unknown.
+}
+
+// DetectStops detects pauses lasting at least minDuration, see DetectPauses. The stop's location is the position of
+// the first stationary record having position, fallback to the last known position before the stop.
+func DetectStops(records []Record, sport typedef.Sport, minDuration time.Duration, sm Summarizer) []Stop {
+	pauses := DetectPauses(records, sport, sm)
+
+	var stops []Stop
+	for i := range pauses {
+		p := &pauses[i]
+		if p.Duration() < minDuration {
+			continue
+		}
+
+		stop := Stop{Pause: *p, PositionLat: math.NaN(), PositionLong: math.NaN()}
+		for j := p.StartIndex; j < p.EndIndex; j++ {
+			if lat, long, ok := records[j].positionDegrees(); ok {
+				stop.PositionLat, stop.PositionLong = lat, long
+				break
+			}
+		}
+		if math.IsNaN(stop.PositionLat) {
+			for j := p.StartIndex - 1; j >= 0; j-- {
+				if lat, long, ok := records[j].positionDegrees(); ok {
+					stop.PositionLat, stop.PositionLong = lat, long
+					break
+				}
+			}
+		}
+
+		stops = append(stops, stop)
+	}
+
+	return stops
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Stop to b, returning the result.
+func (s *Stop) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"startIndex":`...)
+	b = strconv.AppendInt(b, int64(s.StartIndex), 10)
+	b = append(b, `,"endIndex":`...)
+	b = strconv.AppendInt(b, int64(s.EndIndex), 10)
+	b = append(b, `,"startTime":`...)
+	b = strconv.AppendQuote(b, s.StartTime.Format(time.RFC3339))
+	b = append(b, `,"duration":`...)
+	b = strconv.AppendFloat(b, s.Duration().Seconds(), 'g', -1, 64)
+	if !math.IsNaN(s.PositionLat) && !math.IsNaN(s.PositionLong) {
+		b = append(b, `,"positionLat":`...)
+		b = strconv.AppendFloat(b, s.PositionLat, 'g', -1, 64)
+		b = append(b, `,"positionLong":`...)
+		b = strconv.AppendFloat(b, s.PositionLong, 'g', -1, 64)
+	}
+	return append(b, '}')
+}
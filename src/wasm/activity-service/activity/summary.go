@@ -8,6 +8,39 @@ import (
 	"github.com/muktihari/fit/profile/typedef"
 )
 
+// Summarizer summarizes records using user-defined settings. The zero value uses the default settings.
+type Summarizer struct {
+	// ToleranceMovingSpeeds overrides the default tolerance moving speed (in m/s) of the given sports,
+	// sports that are not specified use the default tolerance.
+	ToleranceMovingSpeeds map[typedef.Sport]float64
+	// ElevationGain is the algorithm to calculate total ascent and descent, nil means ElevationGainSmoothed.
+	ElevationGain ElevationGain
+}
+
+// ElevationGainAlgorithm returns the algorithm used to calculate total ascent and descent.
+func (sm Summarizer) ElevationGainAlgorithm() ElevationGain {
+	if sm.ElevationGain == nil {
+		return ElevationGainSmoothed{}
+	}
+	return sm.ElevationGain
+}
+
+// ToleranceMovingSpeed returns the tolerance moving speed of given sport.
+func (sm Summarizer) ToleranceMovingSpeed(sport typedef.Sport) float64 {
+	if speed, ok := sm.ToleranceMovingSpeeds[sport]; ok {
+		return speed
+	}
+	return ToleranceMovingSpeed(sport)
+}
+
+// IsConsideredMoving check whether given speed in a given sport is considered moving.
+func (sm Summarizer) IsConsideredMoving(sport typedef.Sport, speed float64) bool {
+	if math.IsNaN(speed) {
+		return false
+	}
+	return speed > sm.ToleranceMovingSpeed(sport)
+}
+
 // AvgMaxSpeed returns average and max speed of the given records.
 func AvgMaxSpeed(records []Record) (avgSpeed, maxSpeed uint16) {
 	avgSpeed, maxSpeed = basetype.Uint16Invalid, basetype.Uint16Invalid
@@ -31,39 +64,50 @@ func AvgMaxSpeed(records []Record) (avgSpeed, maxSpeed uint16) {
 	return uint16(avg / count), maxSpeed
 }
 
-// TotalAscentAndDescent calculate TotalAscent and TotalDescent from records.
+// TotalAscentAndDescent calculate TotalAscent and TotalDescent from records using the default ElevationGain algorithm.
 func TotalAscentAndDescent(records []Record) (totalAscent, totalDescent uint16) {
-	var ascent, descent float64
-	var hasAltitude bool
-	for i := 0; i < len(records)-1; i++ {
-		rec := &records[i]
-		if math.IsNaN(rec.SmoothedAltitude) {
+	return Summarizer{}.TotalAscentAndDescent(records)
+}
+
+// TotalAscentAndDescent calculate TotalAscent and TotalDescent from records using the summarizer's ElevationGain algorithm.
+func (sm Summarizer) TotalAscentAndDescent(records []Record) (totalAscent, totalDescent uint16) {
+	ascent, descent, ok := sm.ElevationGainAlgorithm().AscentAndDescent(records)
+	if !ok {
+		return basetype.Uint16Invalid, basetype.Uint16Invalid
+	}
+	return uint16(math.Round(ascent)), uint16(math.Round(descent))
+}
+
+// AvgGradeAdjustedPace calculates average grade-adjusted pace in seconds per km from records having pace,
+// intervals longer than maxRecordInterval are skipped. It returns NaN if there is none.
+func AvgGradeAdjustedPace(records []Record) float64 {
+	var elapsed, adjustedDistance float64
+	for i := 1; i < len(records); i++ {
+		rec, prev := &records[i], &records[i-1]
+		if math.IsNaN(rec.Pace) || rec.Distance == basetype.Uint32Invalid || rec.Timestamp.IsZero() ||
+			prev.Distance == basetype.Uint32Invalid || prev.Timestamp.IsZero() {
 			continue
 		}
-		hasAltitude = true
-		// Find next non-nil altitude
-		for j := i + 1; j < len(records); j++ {
-			next := &records[j]
-			if !math.IsNaN(rec.SmoothedAltitude) {
-				delta := next.SmoothedAltitude - rec.SmoothedAltitude
-				if delta > 0 {
-					ascent += delta
-				} else {
-					descent += math.Abs(delta)
-				}
-				i = j - 1 // move cursor
-				break
-			}
+		d := rec.Timestamp.Sub(prev.Timestamp)
+		if d > maxRecordInterval { // the device was paused or lost the signal
+			continue
 		}
+		elapsed += d.Seconds()
+		adjustedDistance += (rec.DistanceScaled() - prev.DistanceScaled()) * GradeAdjustedFactor(rec.Grade)
 	}
-	if !hasAltitude {
-		return basetype.Uint16Invalid, basetype.Uint16Invalid
+	if elapsed == 0 || adjustedDistance <= 0 {
+		return math.NaN()
 	}
-	return uint16(math.Round(ascent)), uint16(math.Round(descent))
+	return elapsed / (adjustedDistance / 1000)
 }
 
-// TotalMovingTime calculates TotalMovingTime from records.
+// TotalMovingTime calculates TotalMovingTime from records using the default tolerance moving speed.
 func TotalMovingTime(records []Record, sport typedef.Sport) (totalMovingTime uint32) {
+	return Summarizer{}.TotalMovingTime(records, sport)
+}
+
+// TotalMovingTime calculates TotalMovingTime from records.
+func (sm Summarizer) TotalMovingTime(records []Record, sport typedef.Sport) (totalMovingTime uint32) {
 	totalMovingTime = basetype.Uint32Invalid
 	for i := 0; i < len(records); i++ {
 		rec := &records[i]
@@ -75,7 +119,7 @@ func TotalMovingTime(records []Record, sport typedef.Sport) (totalMovingTime uin
 			next := &records[j]
 			if !next.Timestamp.IsZero() {
 				delta := next.Timestamp.Sub(rec.Timestamp).Seconds()
-				if IsConsideredMoving(sport, rec.SpeedScaled()) {
+				if sm.IsConsideredMoving(sport, rec.SpeedScaled()) {
 					totalMovingTime += uint32(scaleoffset.Discard(delta, 1000, 0))
 				}
 				i = j - 1 // move cursor
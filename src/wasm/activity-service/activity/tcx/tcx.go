@@ -143,6 +143,9 @@ loop:
 		}
 
 		// Preprocessing...
+		fixedPositions := s.preprocessor.RemoveGPSOutliers(sport, records)
+		s.preprocessor.SmoothingPosition(records)
+		s.preprocessor.CorrectElevation(records)
 		s.preprocessor.CalculateDistanceAndSpeed(records)
 		if activity.HasPace(sport) {
 			s.preprocessor.CalculatePace(sport, records)
@@ -150,11 +153,21 @@ loop:
 
 		s.preprocessor.SmoothingElevation(records)
 		s.preprocessor.CalculateGrade(records)
+		if activity.HasPace(sport) {
+			s.preprocessor.CalculateGradeAdjustedPace(records)
+		}
 
 		// We can only calculate laps' summary after preprocessing
+		// recordsByLap holds copies of records before preprocessing, point them to the preprocessed records.
+		var cur int
+		for i := range recordsByLap {
+			n := len(recordsByLap[i])
+			recordsByLap[i] = records[cur : cur+n]
+			cur += n
+		}
 		for i := range laps {
 			lap := &laps[i]
-			lapFromRecords := activity.NewLapFromRecords(recordsByLap[i], sport)
+			lapFromRecords := activity.NewLapFromRecords(recordsByLap[i], sport, s.preprocessor.Summarizer())
 			aggregator.Fill(lap.Lap, lapFromRecords.Lap)
 		}
 
@@ -169,7 +182,8 @@ loop:
 
 		session.Laps = laps
 		session.Records = records
-		session.Summarize()
+		session.FixedPositions = fixedPositions
+		session.Summarize(s.preprocessor.Summarizer())
 
 		sessions = append(sessions, session)
 
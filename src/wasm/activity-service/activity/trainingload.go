@@ -0,0 +1,139 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+)
+
+// Training load time constants in days.
+const (
+	AcuteTrainingLoadDays   = 7
+	ChronicTrainingLoadDays = 42
+)
+
+// TRIMP calculates Banister's training impulse from records' heart rate: the sum of duration in minutes multiplied by
+// heart rate reserve ratio weighted exponentially, using the gender-specific weighting factor.
+// It returns NaN if records have no heart rate or resting heart rate is not below max heart rate.
+func TRIMP(records []Record, restingHeartRate, maxHeartRate uint8, female bool) float64 {
+	if restingHeartRate >= maxHeartRate {
+		return math.NaN()
+	}
+
+	a, b := 0.64, 1.92
+	if female {
+		a, b = 0.86, 1.67
+	}
+
+	heartRates := secondSeries(records, func(rec *Record) float64 {
+		if rec.HeartRate == basetype.Uint8Invalid {
+			return math.NaN()
+		}
+		return float64(rec.HeartRate)
+	})
+
+	var trimp float64
+	var hasHeartRate bool
+	for _, hr := range heartRates {
+		if math.IsNaN(hr) {
+			continue
+		}
+		hasHeartRate = true
+		ratio := (hr - float64(restingHeartRate)) / float64(maxHeartRate-restingHeartRate)
+		ratio = math.Max(0, math.Min(1, ratio))
+		trimp += 1.0 / 60 * ratio * a * math.Exp(b*ratio)
+	}
+	if !hasHeartRate {
+		return math.NaN()
+	}
+
+	return trimp
+}
+
+// DailyTrainingLoad is the training load of a day.
+type DailyTrainingLoad struct {
+	Date time.Time // Midnight of the day.
+	Load float64   // Sum of training load (TSS or TRIMP) of the day.
+	ATL  float64   // Acute Training Load (fatigue), exponentially weighted average of 7 days.
+	CTL  float64   // Chronic Training Load (fitness), exponentially weighted average of 42 days.
+	TSB  float64   // Training Stress Balance (form): previous day's CTL - ATL.
+}
+
+// NewDailyTrainingLoads rolls the training loads of the given dates into daily ATL, CTL and TSB covering every day
+// from the earliest to the latest date, days without training have zero load. Dates and loads should have the same length.
+func NewDailyTrainingLoads(dates []time.Time, loads []float64) []DailyTrainingLoad {
+	if len(dates) == 0 {
+		return nil
+	}
+
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+
+	first, last := day(dates[0]), day(dates[0])
+	for i := range dates {
+		d := day(dates[i])
+		if d.Before(first) {
+			first = d
+		}
+		if d.After(last) {
+			last = d
+		}
+	}
+
+	n := int(last.Sub(first).Hours()/24) + 1
+	dailyLoads := make([]DailyTrainingLoad, n)
+	for i := range dailyLoads {
+		dailyLoads[i].Date = first.AddDate(0, 0, i)
+	}
+	for i := range dates {
+		if math.IsNaN(loads[i]) {
+			continue
+		}
+		dailyLoads[int(day(dates[i]).Sub(first).Hours()/24)].Load += loads[i]
+	}
+
+	acuteDecay := 1 - math.Exp(-1.0/AcuteTrainingLoadDays)
+	chronicDecay := 1 - math.Exp(-1.0/ChronicTrainingLoadDays)
+
+	var atl, ctl float64
+	for i := range dailyLoads {
+		dl := &dailyLoads[i]
+		dl.TSB = ctl - atl
+		atl += (dl.Load - atl) * acuteDecay
+		ctl += (dl.Load - ctl) * chronicDecay
+		dl.ATL, dl.CTL = atl, ctl
+	}
+
+	return dailyLoads
+}
+
+// MarshalAppendJSON appends the JSON format encoding of DailyTrainingLoad to b, returning the result.
+func (d *DailyTrainingLoad) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"date":`...)
+	b = strconv.AppendQuote(b, d.Date.Format(time.DateOnly))
+	b = append(b, `,"load":`...)
+	b = strconv.AppendFloat(b, d.Load, 'f', 1, 64)
+	b = append(b, `,"atl":`...)
+	b = strconv.AppendFloat(b, d.ATL, 'f', 1, 64)
+	b = append(b, `,"ctl":`...)
+	b = strconv.AppendFloat(b, d.CTL, 'f', 1, 64)
+	b = append(b, `,"tsb":`...)
+	b = strconv.AppendFloat(b, d.TSB, 'f', 1, 64)
+	return append(b, '}')
+}
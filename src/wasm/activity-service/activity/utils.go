@@ -28,7 +28,7 @@ const (
 	ToleranceMovingSpeedCyclingLikeSport = 1.41   // = 5.07 km/h
 )
 
-// IsConsideredMoving check whether given speed in a given sport is considered moving.
+// IsConsideredMoving check whether given speed in a given sport is considered moving using the default tolerance.
 func IsConsideredMoving(sport typedef.Sport, speed float64) bool {
 	if math.IsNaN(speed) {
 		return false
@@ -36,7 +36,7 @@ func IsConsideredMoving(sport typedef.Sport, speed float64) bool {
 	return speed > ToleranceMovingSpeed(sport)
 }
 
-// ToleranceMovingSpeed returns the tolerance moving speed of given record.
+// ToleranceMovingSpeed returns the default tolerance moving speed of given sport.
 func ToleranceMovingSpeed(sport typedef.Sport) float64 {
 	switch sport {
 	case typedef.SportRunning:
@@ -50,6 +50,30 @@ func ToleranceMovingSpeed(sport typedef.Sport) float64 {
 	}
 }
 
+const (
+	MaxSpeedSlowMovingSport  = 8.33  // = 30 km/h
+	MaxSpeedRunningLikeSport = 12.5  // = 45 km/h
+	MaxSpeedCyclingLikeSport = 33.33 // = 120 km/h
+	MaxSpeedGenericSport     = 83.33 // = 300 km/h
+)
+
+// MaxPlausibleSpeed returns the maximum speed that is physically possible for the given sport,
+// anything faster than this is considered as GPS error.
+func MaxPlausibleSpeed(sport typedef.Sport) float64 {
+	switch sport {
+	case typedef.SportRunning:
+		return MaxSpeedRunningLikeSport
+	case typedef.SportCycling:
+		return MaxSpeedCyclingLikeSport
+	case typedef.SportHiking, typedef.SportWalking, typedef.SportSwimming:
+		return MaxSpeedSlowMovingSport
+	default:
+		// Generic: since we don't know the specific sport (it can be motorsport or even flying),
+		// let's only remove positions that are very unlikely to be achieved.
+		return MaxSpeedGenericSport
+	}
+}
+
 // HasPace check whether given sport has pace for analytic.
 func HasPace(sport typedef.Sport) bool {
 	switch sport {
@@ -64,6 +88,18 @@ func HasPace(sport typedef.Sport) bool {
 	}
 }
 
+// GradeAdjustedFactor returns the ratio of the energy cost of running on the given grade (in percent) to the energy cost
+// of running on flat ground, using Minetti et al. (2002) energy cost model. Multiplying the speed with this factor
+// gives the equivalent speed on flat ground. Grade is clamped to ±45% since the model is only valid within that range.
+func GradeAdjustedFactor(grade float64) float64 {
+	if math.IsNaN(grade) {
+		return 1
+	}
+	i := math.Max(-0.45, math.Min(0.45, grade/100))
+	cost := 155.4*math.Pow(i, 5) - 30.4*math.Pow(i, 4) - 43.3*math.Pow(i, 3) + 46.3*i*i + 19.5*i + 3.6
+	return cost / 3.6
+}
+
 func isBelong(timestamp, startTime, endTime time.Time) bool {
 	if timestamp.Equal(startTime) {
 		return true
@@ -0,0 +1,88 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+
+	"github.com/muktihari/fit/profile/basetype"
+)
+
+// CalculateWPrimeBalance calculates W' balance (in joules) of each record using Skiba's differential model:
+// W' is depleted by the work done above critical power and recovers exponentially below it, the recovery rate
+// is proportional to how far the power is below critical power. Records without power are treated as 0 W
+// (e.g. coasting), so do gaps between records. It returns false and leaves the records untouched if
+// cp or wPrime is not positive or the records have no power.
+func CalculateWPrimeBalance(records []Record, cp, wPrime float64) bool {
+	if cp <= 0 || wPrime <= 0 {
+		return false
+	}
+
+	var hasPower bool
+	for i := range records {
+		if records[i].Power != basetype.Uint16Invalid {
+			hasPower = true
+			break
+		}
+	}
+	if !hasPower {
+		return false
+	}
+
+	wbal := wPrime
+	prev := -1
+	for i := range records {
+		rec := &records[i]
+		if rec.Timestamp.IsZero() {
+			continue
+		}
+
+		if prev != -1 {
+			dt := rec.Timestamp.Sub(records[prev].Timestamp).Seconds()
+
+			var power float64
+			if rec.Power != basetype.Uint16Invalid && dt <= maxRecordInterval.Seconds() {
+				power = float64(rec.Power)
+			}
+
+			if power > cp {
+				wbal -= (power - cp) * dt
+			} else if dt > 0 {
+				// Exact solution of dW'bal/dt = (W' - W'bal) * (CP - P) / W' for constant P within dt.
+				wbal = wPrime - (wPrime-wbal)*math.Exp(-(cp-power)*dt/wPrime)
+			}
+		}
+
+		rec.WPrimeBalance = wbal
+		prev = i
+	}
+
+	return true
+}
+
+// MinWPrimeBalance returns the lowest W' balance of the records, NaN if W' balance is not calculated.
+func MinWPrimeBalance(records []Record) float64 {
+	lowest := math.NaN()
+	for i := range records {
+		if math.IsNaN(records[i].WPrimeBalance) {
+			continue
+		}
+		if math.IsNaN(lowest) || records[i].WPrimeBalance < lowest {
+			lowest = records[i].WPrimeBalance
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,109 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/openivity/activity-service/activity"
+)
+
+func TestCalculateWPrimeBalance(t *testing.T) {
+	const cp, wPrime = 200, 20000
+
+	tt := []struct {
+		name     string
+		offsets  []int
+		powers   []uint16
+		cp       float64
+		wPrime   float64
+		ok       bool
+		expected []float64
+	}{
+		{
+			name:     "depleted above cp",
+			offsets:  []int{0, 1, 2, 10},
+			powers:   []uint16{300, 300, 300, 300},
+			cp:       cp,
+			wPrime:   wPrime,
+			ok:       true,
+			expected: []float64{20000, 19900, 19800, 19000},
+		},
+		{
+			name:     "recovered below cp",
+			offsets:  []int{0, 10, 20},
+			powers:   []uint16{0, 300, 0},
+			cp:       cp,
+			wPrime:   wPrime,
+			ok:       true,
+			expected: []float64{20000, 19000, 20000 - 1000*math.Exp(-0.1)},
+		},
+		{
+			name:     "missing power and gap are treated as 0 W",
+			offsets:  []int{0, 10, 20, 110},
+			powers:   []uint16{0, 300, basetype.Uint16Invalid, 400},
+			cp:       cp,
+			wPrime:   wPrime,
+			ok:       true,
+			expected: []float64{20000, 19000, 20000 - 1000*math.Exp(-0.1), 20000 - 1000*math.Exp(-0.1)*math.Exp(-0.9)},
+		},
+		{
+			name:     "no power",
+			offsets:  []int{0, 1},
+			powers:   []uint16{basetype.Uint16Invalid, basetype.Uint16Invalid},
+			cp:       cp,
+			wPrime:   wPrime,
+			ok:       false,
+			expected: []float64{math.NaN(), math.NaN()},
+		},
+		{
+			name:     "cp is not specified",
+			offsets:  []int{0, 1},
+			powers:   []uint16{300, 300},
+			cp:       0,
+			wPrime:   wPrime,
+			ok:       false,
+			expected: []float64{math.NaN(), math.NaN()},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			records := newRecords(tc.offsets, func(i int, rec *mesgdef.Record) { rec.SetPower(tc.powers[i]) })
+			if ok := activity.CalculateWPrimeBalance(records, tc.cp, tc.wPrime); ok != tc.ok {
+				t.Fatalf("expected ok: %t, got: %t", tc.ok, ok)
+			}
+			for i := range records {
+				if !equalFloat(records[i].WPrimeBalance, tc.expected[i]) {
+					t.Fatalf("[%d]: expected: %g, got: %g", i, tc.expected[i], records[i].WPrimeBalance)
+				}
+			}
+
+			lowest := math.NaN()
+			for _, v := range tc.expected {
+				if !math.IsNaN(v) && (math.IsNaN(lowest) || v < lowest) {
+					lowest = v
+				}
+			}
+			if v := activity.MinWPrimeBalance(records); !equalFloat(v, lowest) {
+				t.Fatalf("min: expected: %g, got: %g", lowest, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package activity
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+)
+
+// maxRecordInterval is the maximum interval between two consecutive records to be counted in analytics
+// such as time in zone, longer interval is considered as a pause.
+const maxRecordInterval = time.Minute
+
+// TimeInZone is time spent in each zone. Zones are defined by its high boundaries following FIT's time_in_zone convention:
+// zone 0 is below Boundaries[0], zone i is between Boundaries[i-1] (inclusive) and Boundaries[i] (exclusive),
+// and the last zone is Boundaries[len(Boundaries)-1] and above. So, len(Times) is always len(Boundaries)+1.
+type TimeInZone struct {
+	Boundaries []float64
+	Times      []time.Duration
+}
+
+// IsValid checks whether TimeInZone has any zone.
+func (t *TimeInZone) IsValid() bool { return len(t.Times) != 0 }
+
+// HeartRateZoneMethod is the method to determine heart rate zones' boundaries.
+type HeartRateZoneMethod byte
+
+const (
+	HeartRateZoneCustom       HeartRateZoneMethod = iota // User-supplied boundaries in bpm.
+	HeartRateZoneMaxHeartRate                            // 60%, 70%, 80% and 90% of max heart rate.
+	HeartRateZoneThreshold                               // 85%, 90%, 95% and 100% of lactate threshold heart rate (Friel).
+	HeartRateZoneReserve                                 // 60%, 70%, 80% and 90% of heart rate reserve above resting heart rate (Karvonen).
+)
+
+// HeartRateZoneBoundaries returns the 4 upper boundaries separating the 5 zones in bpm calculated using the given
+// method, it returns nil if the heart rate required by the method is not specified (zero).
+func HeartRateZoneBoundaries(method HeartRateZoneMethod, maxHeartRate, restingHeartRate, thresholdHeartRate uint8) []float64 {
+	var base, scale float64
+	var percentages []float64
+	switch method {
+	case HeartRateZoneMaxHeartRate:
+		base, scale = 0, float64(maxHeartRate)
+		percentages = []float64{60, 70, 80, 90}
+	case HeartRateZoneThreshold:
+		base, scale = 0, float64(thresholdHeartRate)
+		percentages = []float64{85, 90, 95, 100}
+	case HeartRateZoneReserve:
+		if restingHeartRate >= maxHeartRate {
+			return nil
+		}
+		base, scale = float64(restingHeartRate), float64(maxHeartRate-restingHeartRate)
+		percentages = []float64{60, 70, 80, 90}
+	default:
+		return nil
+	}
+	if scale == 0 {
+		return nil
+	}
+
+	boundaries := make([]float64, len(percentages))
+	for i := range percentages {
+		boundaries[i] = math.Round(base + scale*percentages[i]/100)
+	}
+	return boundaries
+}
+
+// NewTimeInHeartRateZone calculates time spent in each heart rate zone from records. Each record's heart rate
+// is counted for the duration until the next record. It returns zero TimeInZone if boundaries is empty or
+// records have no heart rate.
+func NewTimeInHeartRateZone(records []Record, boundaries []float64) TimeInZone {
+	return newTimeInZone(records, boundaries, func(rec *Record) float64 {
+		if rec.HeartRate == basetype.Uint8Invalid {
+			return math.NaN()
+		}
+		return float64(rec.HeartRate)
+	})
+}
+
+func newTimeInZone(records []Record, boundaries []float64, value func(rec *Record) float64) TimeInZone {
+	if len(boundaries) == 0 {
+		return TimeInZone{}
+	}
+
+	times := make([]time.Duration, len(boundaries)+1)
+	var hasValue bool
+	for i := 0; i < len(records)-1; i++ {
+		rec := &records[i]
+		v := value(rec)
+		if rec.Timestamp.IsZero() || math.IsNaN(v) {
+			continue
+		}
+
+		var next *Record
+		for j := i + 1; j < len(records); j++ {
+			if !records[j].Timestamp.IsZero() {
+				next = &records[j]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		d := next.Timestamp.Sub(rec.Timestamp)
+		if d <= 0 || d > maxRecordInterval {
+			continue
+		}
+
+		zone := len(boundaries)
+		for k := range boundaries {
+			if v < boundaries[k] {
+				zone = k
+				break
+			}
+		}
+		times[zone] += d
+		hasValue = true
+	}
+
+	if !hasValue {
+		return TimeInZone{}
+	}
+
+	return TimeInZone{Boundaries: boundaries, Times: times}
+}
+
+// MarshalAppendJSON appends the JSON format encoding of TimeInZone to b, returning the result.
+// Times are serialized in seconds.
+func (t *TimeInZone) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"boundaries":[`...)
+	for i := range t.Boundaries {
+		b = strconv.AppendFloat(b, t.Boundaries[i], 'g', -1, 64)
+		if i != len(t.Boundaries)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, `],"times":[`...)
+	for i := range t.Times {
+		b = strconv.AppendFloat(b, t.Times[i].Seconds(), 'g', -1, 64)
+		if i != len(t.Times)-1 {
+			b = append(b, ',')
+		}
+	}
+	return append(b, "]}"...)
+}
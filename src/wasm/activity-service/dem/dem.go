@@ -0,0 +1,137 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package dem reads Digital Elevation Model tiles supplied by the user, such as SRTM (.hgt) and GeoTIFF (.tif),
+// so elevation can be sampled at any coordinate covered by the tiles without any network access.
+package dem
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// Tile is a single elevation tile.
+type Tile interface {
+	// Elevation returns elevation in meters at given coordinate (in degrees), ok is false if the coordinate
+	// is outside the tile or there is no data at that coordinate.
+	Elevation(lat, lon float64) (elevation float64, ok bool)
+}
+
+// Model is a digital elevation model composed of tiles. Zero value is ready to use.
+type Model struct {
+	tiles []Tile
+}
+
+// Add adds tiles into the model.
+func (m *Model) Add(tiles ...Tile) { m.tiles = append(m.tiles, tiles...) }
+
+// Len returns the number of tiles in the model.
+func (m *Model) Len() int { return len(m.tiles) }
+
+// Elevation returns elevation in meters at given coordinate (in degrees) from the first tile covering it.
+func (m *Model) Elevation(lat, lon float64) (elevation float64, ok bool) {
+	for i := range m.tiles {
+		if elevation, ok = m.tiles[i].Elevation(lat, lon); ok {
+			return elevation, true
+		}
+	}
+	return math.NaN(), false
+}
+
+// Decode decodes b into a Tile, the format is determined by the file name's extension:
+// ".hgt" for SRTM and ".tif" or ".tiff" for GeoTIFF.
+func Decode(name string, b []byte) (Tile, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".hgt":
+		return DecodeHGT(filepath.Base(name), b)
+	case ".tif", ".tiff":
+		return DecodeGeoTIFF(b)
+	default:
+		return nil, fmt.Errorf("%q: %w", name, ErrUnsupportedFormat)
+	}
+}
+
+// Open reads the file of the given path and decodes it into a Tile.
+func Open(path string) (Tile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(path, b)
+}
+
+// grid is a regular grid of elevation values in row-major order, the first row is the northernmost.
+type grid struct {
+	north, west   float64 // coordinate of the first value (center of the pixel)
+	latStep       float64 // distance between rows in degrees
+	lonStep       float64 // distance between columns in degrees
+	rows, cols    int
+	values        []float64
+	noData        float64
+	hasNoDataFlag bool
+}
+
+func (g *grid) at(row, col int) (float64, bool) {
+	v := g.values[row*g.cols+col]
+	if math.IsNaN(v) || (g.hasNoDataFlag && v == g.noData) {
+		return 0, false
+	}
+	return v, true
+}
+
+// Elevation returns elevation using bilinear interpolation of the four surrounding values.
+func (g *grid) Elevation(lat, lon float64) (float64, bool) {
+	y := (g.north - lat) / g.latStep
+	x := (lon - g.west) / g.lonStep
+	if y < 0 || x < 0 || y > float64(g.rows-1) || x > float64(g.cols-1) {
+		return math.NaN(), false
+	}
+
+	row, col := int(y), int(x)
+	if row == g.rows-1 {
+		row--
+	}
+	if col == g.cols-1 {
+		col--
+	}
+	if row < 0 || col < 0 { // grid has only 1 row or 1 column
+		return math.NaN(), false
+	}
+	dy, dx := y-float64(row), x-float64(col)
+
+	v00, ok00 := g.at(row, col)
+	v01, ok01 := g.at(row, col+1)
+	v10, ok10 := g.at(row+1, col)
+	v11, ok11 := g.at(row+1, col+1)
+	if !ok00 || !ok01 || !ok10 || !ok11 {
+		// Let's use the nearest value instead of interpolating with void.
+		nearestRow, nearestCol := int(math.Round(y)), int(math.Round(x))
+		v, ok := g.at(nearestRow, nearestCol)
+		if !ok {
+			return math.NaN(), false
+		}
+		return v, true
+	}
+
+	top := v00*(1-dx) + v01*dx
+	bottom := v10*(1-dx) + v11*dx
+	return top*(1-dy) + bottom*dy, true
+}
@@ -0,0 +1,242 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package dem_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/openivity/activity-service/dem"
+)
+
+// makeHGT makes 3x3 SRTM tile:
+//
+//	100 200 300
+//	400 500 600
+//	700 800 void
+func makeHGT() []byte {
+	values := []int16{100, 200, 300, 400, 500, 600, 700, 800, -32768}
+	b := make([]byte, len(values)*2)
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[i*2:], uint16(v))
+	}
+	return b
+}
+
+func TestDecodeHGT(t *testing.T) {
+	tile, err := dem.Decode("S08E110.hgt", makeHGT())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		lat, lon  float64
+		elevation float64
+		ok        bool
+	}{
+		{lat: -7, lon: 110, elevation: 100, ok: true},
+		{lat: -7, lon: 111, elevation: 300, ok: true},
+		{lat: -7.5, lon: 110.5, elevation: 500, ok: true},
+		{lat: -7.25, lon: 110.25, elevation: 300, ok: true},
+		{lat: -7.75, lon: 110.75, elevation: math.NaN(), ok: false}, // nearest is void
+		{lat: -6.5, lon: 110.5, elevation: math.NaN(), ok: false},   // outside
+	}
+
+	for _, tc := range tt {
+		elevation, ok := tile.Elevation(tc.lat, tc.lon)
+		if ok != tc.ok {
+			t.Fatalf("(%g, %g): expected ok: %t, got: %t", tc.lat, tc.lon, tc.ok, ok)
+		}
+		if ok && math.Abs(elevation-tc.elevation) > 1e-9 {
+			t.Fatalf("(%g, %g): expected: %g, got: %g", tc.lat, tc.lon, tc.elevation, elevation)
+		}
+	}
+}
+
+func TestDecodeHGTInvalid(t *testing.T) {
+	if _, err := dem.DecodeHGT("X08E110.hgt", makeHGT()); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if _, err := dem.DecodeHGT("S08E110.hgt", make([]byte, 3)); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if _, err := dem.Decode("S08E110.png", makeHGT()); !errors.Is(err, dem.ErrUnsupportedFormat) {
+		t.Fatalf("expected: %v, got: %v", dem.ErrUnsupportedFormat, err)
+	}
+}
+
+// makeGeoTIFF makes little-endian 2x2 float32 GeoTIFF (PixelIsPoint) with top-left at (lat: -7, lon: 110)
+// and 0.5 degree resolution:
+//
+//	10 20
+//	30 40
+func makeGeoTIFF() []byte {
+	order := binary.LittleEndian
+	type entry struct {
+		tag, typ uint16
+		count    uint32
+		value    []byte
+	}
+	u16 := func(vs ...uint16) []byte {
+		b := make([]byte, len(vs)*2)
+		for i, v := range vs {
+			order.PutUint16(b[i*2:], v)
+		}
+		return b
+	}
+	u32 := func(vs ...uint32) []byte {
+		b := make([]byte, len(vs)*4)
+		for i, v := range vs {
+			order.PutUint32(b[i*4:], v)
+		}
+		return b
+	}
+	f64 := func(vs ...float64) []byte {
+		b := make([]byte, len(vs)*8)
+		for i, v := range vs {
+			order.PutUint64(b[i*8:], math.Float64bits(v))
+		}
+		return b
+	}
+
+	pixels := make([]byte, 0, 16)
+	for _, v := range []float32{10, 20, 30, 40} {
+		pixels = order.AppendUint32(pixels, math.Float32bits(v))
+	}
+
+	entries := []entry{
+		{tag: 256, typ: 3, count: 1, value: u16(2)},
+		{tag: 257, typ: 3, count: 1, value: u16(2)},
+		{tag: 258, typ: 3, count: 1, value: u16(32)},
+		{tag: 259, typ: 3, count: 1, value: u16(1)},
+		{tag: 273, typ: 4, count: 1, value: nil}, // strip offset, filled below
+		{tag: 278, typ: 3, count: 1, value: u16(2)},
+		{tag: 279, typ: 4, count: 1, value: u32(16)},
+		{tag: 339, typ: 3, count: 1, value: u16(3)},
+		{tag: 33550, typ: 12, count: 3, value: f64(0.5, 0.5, 0)},
+		{tag: 33922, typ: 12, count: 6, value: f64(0, 0, 0, 110, -7, 0)},
+		{tag: 34735, typ: 3, count: 8, value: u16(1, 1, 0, 1, 1025, 0, 1, 2)}, // PixelIsPoint
+	}
+
+	const ifdOffset = 8
+	extraOffset := ifdOffset + 2 + len(entries)*12 + 4
+	var extra []byte
+	stripOffset := uint32(extraOffset)
+	for i := range entries {
+		if len(entries[i].value) > 4 {
+			stripOffset += uint32(len(entries[i].value))
+		}
+	}
+	entries[4].value = u32(stripOffset)
+
+	b := []byte("II")
+	b = order.AppendUint16(b, 42)
+	b = order.AppendUint32(b, ifdOffset)
+	b = order.AppendUint16(b, uint16(len(entries)))
+	for i := range entries {
+		e := &entries[i]
+		b = order.AppendUint16(b, e.tag)
+		b = order.AppendUint16(b, e.typ)
+		b = order.AppendUint32(b, e.count)
+		if len(e.value) > 4 {
+			b = order.AppendUint32(b, uint32(extraOffset+len(extra)))
+			extra = append(extra, e.value...)
+			continue
+		}
+		value := make([]byte, 4)
+		copy(value, e.value)
+		b = append(b, value...)
+	}
+	b = order.AppendUint32(b, 0) // next ifd
+	b = append(b, extra...)
+	b = append(b, pixels...)
+	return b
+}
+
+func TestDecodeGeoTIFF(t *testing.T) {
+	tile, err := dem.Decode("dem.tif", makeGeoTIFF())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		lat, lon  float64
+		elevation float64
+		ok        bool
+	}{
+		{lat: -7, lon: 110, elevation: 10, ok: true},
+		{lat: -7.5, lon: 110.5, elevation: 40, ok: true},
+		{lat: -7.25, lon: 110.25, elevation: 25, ok: true},
+		{lat: -8, lon: 110, elevation: math.NaN(), ok: false},
+	}
+
+	for _, tc := range tt {
+		elevation, ok := tile.Elevation(tc.lat, tc.lon)
+		if ok != tc.ok {
+			t.Fatalf("(%g, %g): expected ok: %t, got: %t", tc.lat, tc.lon, tc.ok, ok)
+		}
+		if ok && math.Abs(elevation-tc.elevation) > 1e-9 {
+			t.Fatalf("(%g, %g): expected: %g, got: %g", tc.lat, tc.lon, tc.elevation, elevation)
+		}
+	}
+}
+
+func TestDecodeGeoTIFFInvalid(t *testing.T) {
+	const ifdEntries = 8 + 2 // ifd offset + number of entries
+
+	tt := []struct {
+		name  string
+		entry int // index of entry to be modified
+		typ   uint16
+		count uint32
+	}{
+		{name: "size wraps uint32", entry: 4, typ: 4, count: 0x40000001},
+		{name: "count exceeds file", entry: 0, typ: 3, count: 1 << 20},
+		{name: "value out of range", entry: 9, typ: 12, count: 1000},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b := makeGeoTIFF()
+			raw := b[ifdEntries+tc.entry*12:]
+			binary.LittleEndian.PutUint16(raw[2:], tc.typ)
+			binary.LittleEndian.PutUint32(raw[4:], tc.count)
+			if _, err := dem.Decode("dem.tif", b); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestModel(t *testing.T) {
+	var model dem.Model
+	if _, ok := model.Elevation(-7, 110); ok {
+		t.Fatalf("expected not ok on empty model")
+	}
+
+	tile, err := dem.Decode("S08E110.hgt", makeHGT())
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.Add(tile)
+
+	elevation, ok := model.Elevation(-7.5, 110.5)
+	if !ok || elevation != 500 {
+		t.Fatalf("expected: 500, got: %g (ok: %t)", elevation, ok)
+	}
+}
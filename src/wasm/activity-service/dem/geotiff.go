@@ -0,0 +1,296 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package dem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags used by GeoTIFF DEM.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagSampleFormat    = 339
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagGeoKeyDirectory = 34735
+	tagGDALNoData      = 42113
+)
+
+// GeoKeys used by GeoTIFF DEM.
+const (
+	geoKeyModelType  = 1024
+	geoKeyRasterType = 1025
+
+	modelTypeProjected = 1
+	rasterPixelIsArea  = 1
+)
+
+const (
+	sampleFormatUint  = 1
+	sampleFormatInt   = 2
+	sampleFormatFloat = 3
+)
+
+// ifdEntry is TIFF's Image File Directory entry.
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+// typeSizes is the size of TIFF's field types in bytes, indexed by the type.
+var typeSizes = [...]uint32{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// DecodeGeoTIFF decodes GeoTIFF in geographic coordinate (e.g. WGS84 degrees) containing single band elevation.
+// Only uncompressed strips or tiles of 16, 32 or 64 bits samples are supported, which is the common format
+// of the DEM tiles. Compressed file should be converted first, e.g. "gdal_translate -co COMPRESS=NONE".
+//
+// ref: https://docs.ogc.org/is/19-008r4/19-008r4.html
+func DecodeGeoTIFF(b []byte) (Tile, error) {
+	if len(b) < 8 {
+		return nil, fmt.Errorf("geotiff: invalid header")
+	}
+
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("geotiff: invalid byte order %q", b[:2])
+	}
+	if magic := order.Uint16(b[2:]); magic != 42 {
+		return nil, fmt.Errorf("geotiff: magic number %d: %w", magic, ErrUnsupportedFormat) // e.g. BigTIFF
+	}
+
+	entries, err := readIFD(b, order, order.Uint32(b[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	tagUint := func(tag uint16, def uint64) uint64 {
+		if vals := entryUints(entries[tag], order); len(vals) > 0 {
+			return vals[0]
+		}
+		return def
+	}
+
+	width, height := int(tagUint(tagImageWidth, 0)), int(tagUint(tagImageLength, 0))
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("geotiff: invalid dimension %dx%d", width, height)
+	}
+	if compression := tagUint(tagCompression, 1); compression != 1 {
+		return nil, fmt.Errorf("geotiff: compression %d: %w", compression, ErrUnsupportedFormat)
+	}
+	if samplesPerPixel := tagUint(tagSamplesPerPixel, 1); samplesPerPixel != 1 {
+		return nil, fmt.Errorf("geotiff: samples per pixel %d: %w", samplesPerPixel, ErrUnsupportedFormat)
+	}
+	bitsPerSample := tagUint(tagBitsPerSample, 1)
+	sampleFormat := tagUint(tagSampleFormat, sampleFormatUint)
+	sample, err := sampleReader(order, bitsPerSample, sampleFormat)
+	if err != nil {
+		return nil, err
+	}
+	bytesPerSample := int(bitsPerSample / 8)
+
+	values := make([]float64, width*height)
+
+	// Pixels are stored either in tiles or in strips, strips is simply a tile with the image's width.
+	tileWidth, tileHeight := int(tagUint(tagTileWidth, 0)), int(tagUint(tagTileLength, 0))
+	offsets := entryUints(entries[tagTileOffsets], order)
+	if tileWidth == 0 || tileHeight == 0 {
+		tileWidth, tileHeight = width, int(tagUint(tagRowsPerStrip, uint64(height)))
+		offsets = entryUints(entries[tagStripOffsets], order)
+	}
+	if tileWidth <= 0 || tileHeight <= 0 || len(offsets) == 0 {
+		return nil, fmt.Errorf("geotiff: missing strips or tiles")
+	}
+	tilesAcross := (width + tileWidth - 1) / tileWidth
+
+	for t, offset := range offsets {
+		top, left := (t/tilesAcross)*tileHeight, (t%tilesAcross)*tileWidth
+		for y := 0; y < tileHeight && top+y < height; y++ {
+			for x := 0; x < tileWidth && left+x < width; x++ {
+				pos := int(offset) + (y*tileWidth+x)*bytesPerSample
+				if pos+bytesPerSample > len(b) {
+					return nil, fmt.Errorf("geotiff: pixel data out of range")
+				}
+				values[(top+y)*width+left+x] = sample(b[pos:])
+			}
+		}
+	}
+
+	scale := entryFloats(entries[tagModelPixelScale], order)
+	tiepoint := entryFloats(entries[tagModelTiepoint], order)
+	if len(scale) < 2 || len(tiepoint) < 6 {
+		return nil, fmt.Errorf("geotiff: missing georeference")
+	}
+
+	geoKeys := parseGeoKeys(entryUints(entries[tagGeoKeyDirectory], order))
+	if geoKeys[geoKeyModelType] == modelTypeProjected {
+		return nil, fmt.Errorf("geotiff: projected coordinate system: %w", ErrUnsupportedFormat)
+	}
+
+	g := &grid{
+		west:    tiepoint[3] - tiepoint[0]*scale[0],
+		north:   tiepoint[4] + tiepoint[1]*scale[1],
+		lonStep: scale[0],
+		latStep: scale[1],
+		rows:    height,
+		cols:    width,
+		values:  values,
+	}
+
+	// RasterType defaults to PixelIsArea where the tiepoint refers to the pixel's corner,
+	// while our grid is based on the pixel's center.
+	rasterType, ok := geoKeys[geoKeyRasterType]
+	if !ok || rasterType == rasterPixelIsArea {
+		g.west += scale[0] / 2
+		g.north -= scale[1] / 2
+	}
+
+	if e, ok := entries[tagGDALNoData]; ok {
+		s := strings.TrimSpace(strings.TrimRight(string(e.data), "\x00"))
+		if noData, err := strconv.ParseFloat(s, 64); err == nil {
+			g.noData, g.hasNoDataFlag = noData, true
+		}
+	}
+
+	return g, nil
+}
+
+func readIFD(b []byte, order binary.ByteOrder, offset uint32) (map[uint16]ifdEntry, error) {
+	if int(offset)+2 > len(b) {
+		return nil, fmt.Errorf("geotiff: ifd offset out of range")
+	}
+	n := int(order.Uint16(b[offset:]))
+	pos := int(offset) + 2
+	if pos+n*12 > len(b) {
+		return nil, fmt.Errorf("geotiff: ifd entries out of range")
+	}
+
+	entries := make(map[uint16]ifdEntry, n)
+	for i := 0; i < n; i++ {
+		raw := b[pos+i*12:]
+		tag, typ, count := order.Uint16(raw), order.Uint16(raw[2:]), order.Uint32(raw[4:])
+		if int(typ) >= len(typeSizes) || typeSizes[typ] == 0 {
+			continue // Unknown type, not the one we need.
+		}
+
+		if uint64(count) > uint64(len(b)) {
+			return nil, fmt.Errorf("geotiff: tag %d count out of range", tag)
+		}
+
+		size := uint64(typeSizes[typ]) * uint64(count) // in uint64 so it can't wrap.
+		data := raw[8:12]
+		if size > 4 {
+			valueOffset := uint64(order.Uint32(raw[8:]))
+			if valueOffset+size > uint64(len(b)) {
+				return nil, fmt.Errorf("geotiff: tag %d value out of range", tag)
+			}
+			data = b[valueOffset : valueOffset+size]
+		}
+		entries[tag] = ifdEntry{typ: typ, count: count, data: data[:size]}
+	}
+
+	return entries, nil
+}
+
+// entryUints returns values of SHORT or LONG entry.
+func entryUints(e ifdEntry, order binary.ByteOrder) []uint64 {
+	var size int
+	switch e.typ {
+	case 3: // SHORT
+		size = 2
+	case 4: // LONG
+		size = 4
+	default:
+		return nil
+	}
+	n := min(int(e.count), len(e.data)/size)
+	vals := make([]uint64, 0, n)
+	for i := 0; i < n; i++ {
+		if size == 2 {
+			vals = append(vals, uint64(order.Uint16(e.data[i*2:])))
+		} else {
+			vals = append(vals, uint64(order.Uint32(e.data[i*4:])))
+		}
+	}
+	return vals
+}
+
+// entryFloats returns values of DOUBLE entry.
+func entryFloats(e ifdEntry, order binary.ByteOrder) []float64 {
+	if e.typ != 12 {
+		return nil
+	}
+	vals := make([]float64, min(int(e.count), len(e.data)/8))
+	for i := range vals {
+		vals[i] = math.Float64frombits(order.Uint64(e.data[i*8:]))
+	}
+	return vals
+}
+
+// parseGeoKeys parses GeoKeyDirectory into map of key id and its value, only keys with inline value are retrieved.
+func parseGeoKeys(dir []uint64) map[uint64]uint64 {
+	keys := make(map[uint64]uint64)
+	if len(dir) < 4 {
+		return keys
+	}
+	n := int(dir[3])
+	for i := 0; i < n && 4+i*4+3 < len(dir); i++ {
+		key := dir[4+i*4:]
+		if key[1] != 0 { // TIFFTagLocation: value is stored in other tag.
+			continue
+		}
+		keys[key[0]] = key[3]
+	}
+	return keys
+}
+
+// sampleReader returns func to read single sample value from b.
+func sampleReader(order binary.ByteOrder, bitsPerSample, sampleFormat uint64) (func(b []byte) float64, error) {
+	switch {
+	case sampleFormat == sampleFormatInt && bitsPerSample == 16:
+		return func(b []byte) float64 { return float64(int16(order.Uint16(b))) }, nil
+	case sampleFormat == sampleFormatUint && bitsPerSample == 16:
+		return func(b []byte) float64 { return float64(order.Uint16(b)) }, nil
+	case sampleFormat == sampleFormatInt && bitsPerSample == 32:
+		return func(b []byte) float64 { return float64(int32(order.Uint32(b))) }, nil
+	case sampleFormat == sampleFormatUint && bitsPerSample == 32:
+		return func(b []byte) float64 { return float64(order.Uint32(b)) }, nil
+	case sampleFormat == sampleFormatFloat && bitsPerSample == 32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(order.Uint32(b))) }, nil
+	case sampleFormat == sampleFormatFloat && bitsPerSample == 64:
+		return func(b []byte) float64 { return math.Float64frombits(order.Uint64(b)) }, nil
+	}
+	return nil, fmt.Errorf("geotiff: %d bits sample format %d: %w", bitsPerSample, sampleFormat, ErrUnsupportedFormat)
+}
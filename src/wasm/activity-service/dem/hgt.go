@@ -0,0 +1,96 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package dem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// hgtVoid is the value used by SRTM to mark no data.
+const hgtVoid = -32768
+
+// DecodeHGT decodes SRTM tile, the name (e.g. N37W122.hgt) is required since the tile's location
+// is only described by its name which is the coordinate of the tile's south-west corner.
+// The tile is a square grid of big-endian int16 values, commonly 1201x1201 (SRTM3) or 3601x3601 (SRTM1).
+//
+// ref: https://www.usgs.gov/centers/eros/science/usgs-eros-archive-digital-elevation-shuttle-radar-topography-mission-srtm
+func DecodeHGT(name string, b []byte) (Tile, error) {
+	lat, lon, err := parseHGTName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(math.Sqrt(float64(len(b) / 2)))
+	if size < 2 || size*size*2 != len(b) {
+		return nil, fmt.Errorf("hgt: invalid size %d bytes", len(b))
+	}
+
+	values := make([]float64, size*size)
+	for i := range values {
+		values[i] = float64(int16(binary.BigEndian.Uint16(b[i*2:])))
+	}
+
+	step := 1 / float64(size-1)
+	return &grid{
+		north:         float64(lat + 1),
+		west:          float64(lon),
+		latStep:       step,
+		lonStep:       step,
+		rows:          size,
+		cols:          size,
+		values:        values,
+		noData:        hgtVoid,
+		hasNoDataFlag: true,
+	}, nil
+}
+
+// parseHGTName parses SRTM tile's name (e.g. N37W122.hgt) into latitude and longitude of south-west corner.
+func parseHGTName(name string) (lat, lon int, err error) {
+	name = strings.ToUpper(name)
+	if len(name) < 7 {
+		return 0, 0, fmt.Errorf("hgt: invalid name %q", name)
+	}
+
+	lat, err = strconv.Atoi(name[1:3])
+	if err != nil {
+		return 0, 0, fmt.Errorf("hgt: invalid latitude in name %q: %w", name, err)
+	}
+	switch name[0] {
+	case 'N':
+	case 'S':
+		lat = -lat
+	default:
+		return 0, 0, fmt.Errorf("hgt: invalid latitude hemisphere in name %q", name)
+	}
+
+	lon, err = strconv.Atoi(name[4:7])
+	if err != nil {
+		return 0, 0, fmt.Errorf("hgt: invalid longitude in name %q: %w", name, err)
+	}
+	switch name[3] {
+	case 'E':
+	case 'W':
+		lon = -lon
+	default:
+		return 0, 0, fmt.Errorf("hgt: invalid longitude hemisphere in name %q", name)
+	}
+
+	return lat, lon, nil
+}
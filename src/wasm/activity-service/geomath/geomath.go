@@ -19,6 +19,12 @@ import (
 	"math"
 )
 
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371000.0
+
+// MetersPerDegree is the length of 1 degree of latitude (or longitude on equator) in meters.
+const MetersPerDegree = EarthRadius * math.Pi / 180
+
 // HaversineDistance returns distance meters between two coordinates calculated using Haversine formula.
 // The Haversine formula can result in an error of up to 0.5% since the Earth is not even a sphere — it’s an oblate ellipsoid.
 //
@@ -39,6 +45,120 @@ func HaversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	return distance * 1000 // in meters
 }
 
+// IntermediatePoint returns the point (in degrees) at given fraction between two coordinates along the great circle,
+// fraction 0 returns the first coordinate and fraction 1 returns the second coordinate.
+//
+// ref: http://www.movable-type.co.uk/scripts/latlong.html#intermediate-point
+func IntermediatePoint(lat1, lon1, lat2, lon2, fraction float64) (lat, lon float64) {
+	rlat1, rlon1 := degreesToRadians(lat1), degreesToRadians(lon1)
+	rlat2, rlon2 := degreesToRadians(lat2), degreesToRadians(lon2)
+
+	// Angular distance
+	a := math.Pow(math.Sin((rlat2-rlat1)/2), 2) + math.Cos(rlat1)*math.Cos(rlat2)*math.Pow(math.Sin((rlon2-rlon1)/2), 2)
+	d := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	if d == 0 {
+		return lat1, lon1
+	}
+
+	a1 := math.Sin((1-fraction)*d) / math.Sin(d)
+	a2 := math.Sin(fraction*d) / math.Sin(d)
+
+	x := a1*math.Cos(rlat1)*math.Cos(rlon1) + a2*math.Cos(rlat2)*math.Cos(rlon2)
+	y := a1*math.Cos(rlat1)*math.Sin(rlon1) + a2*math.Cos(rlat2)*math.Sin(rlon2)
+	z := a1*math.Sin(rlat1) + a2*math.Sin(rlat2)
+
+	rlat := math.Atan2(z, math.Sqrt(x*x+y*y))
+	rlon := math.Atan2(y, x)
+
+	return radiansToDegrees(rlat), radiansToDegrees(rlon)
+}
+
+// MercatorMaxLatitude is the latitude in degrees where Web Mercator projection is cut off to make the map square.
+const MercatorMaxLatitude = 85.05112878
+
+// WebMercator projects the coordinate (in degrees) to Web Mercator world coordinate normalized to [0, 1],
+// x grows eastward from -180° longitude and y grows southward from MercatorMaxLatitude.
+//
+// ref: https://en.wikipedia.org/wiki/Web_Mercator_projection
+func WebMercator(lat, lon float64) (x, y float64) {
+	lat = math.Max(-MercatorMaxLatitude, math.Min(MercatorMaxLatitude, lat))
+	rlat := degreesToRadians(lat)
+
+	x = (lon + 180) / 360
+	y = (1 - math.Log(math.Tan(rlat)+1/math.Cos(rlat))/math.Pi) / 2
+
+	return x, y
+}
+
 func degreesToRadians(deg float64) float64 {
 	return deg * (math.Pi / 180)
 }
+
+func radiansToDegrees(rad float64) float64 {
+	return rad * (180 / math.Pi)
+}
+
+// DouglasPeucker simplifies polyline of the given coordinates (in degrees) using Ramer-Douglas-Peucker algorithm
+// and returns indexes of the points to keep in ascending order. The first and the last points are always kept.
+// The tolerance is the maximum distance in meters between the simplified polyline and the removed points.
+//
+// ref: https://en.wikipedia.org/wiki/Ramer%E2%80%93Douglas%E2%80%93Peucker_algorithm
+func DouglasPeucker(lats, lons []float64, tolerance float64) []int {
+	n := len(lats)
+	if n <= 2 {
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	}
+
+	keep := make([]bool, n)
+	keep[0], keep[n-1] = true, true
+
+	// Iterative using stack rather than recursive since the track can contain hundred thousands of points.
+	stack := [][2]int{{0, n - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		var maxDistance float64
+		var index int
+		for i := first + 1; i < last; i++ {
+			d := crossTrackDistance(lats[i], lons[i], lats[first], lons[first], lats[last], lons[last])
+			if d > maxDistance {
+				maxDistance, index = d, i
+			}
+		}
+
+		if maxDistance > tolerance {
+			keep[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+
+	indexes := make([]int, 0)
+	for i := range keep {
+		if keep[i] {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// crossTrackDistance returns distance in meters between point p and line segment a-b, calculated on
+// an equirectangular projection centered at point a which is accurate enough for short distances.
+func crossTrackDistance(lat, lon, latA, lonA, latB, lonB float64) float64 {
+	k := math.Cos(degreesToRadians(latA))
+	px, py := (lon-lonA)*k*MetersPerDegree, (lat-latA)*MetersPerDegree
+	bx, by := (lonB-lonA)*k*MetersPerDegree, (latB-latA)*MetersPerDegree
+
+	segmentLength := bx*bx + by*by
+	if segmentLength == 0 {
+		return math.Hypot(px, py)
+	}
+
+	t := (px*bx + py*by) / segmentLength
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(px-t*bx, py-t*by)
+}
@@ -48,3 +48,99 @@ func TestHaversineDistance(t *testing.T) {
 		}
 	}
 }
+
+func TestIntermediatePoint(t *testing.T) {
+	tt := []struct {
+		lat1, lon1 float64
+		lat2, lon2 float64
+		fraction   float64
+		lat, lon   float64
+	}{
+		{lat1: -7.2, lon1: 109.9, lat2: -7.2, lon2: 109.9, fraction: 0.5, lat: -7.2, lon: 109.9},
+		{lat1: -7.2, lon1: 109.9, lat2: -7.4, lon2: 110.1, fraction: 0, lat: -7.2, lon: 109.9},
+		{lat1: -7.2, lon1: 109.9, lat2: -7.4, lon2: 110.1, fraction: 1, lat: -7.4, lon: 110.1},
+		{lat1: -7.2, lon1: 109.9, lat2: -7.4, lon2: 110.1, fraction: 0.5, lat: -7.3, lon: 110},
+		{lat1: 0, lon1: 0, lat2: 0, lon2: 90, fraction: 0.5, lat: 0, lon: 45},
+	}
+
+	for _, tc := range tt {
+		lat, lon := geomath.IntermediatePoint(tc.lat1, tc.lon1, tc.lat2, tc.lon2, tc.fraction)
+		lat, lon = math.Round(lat*1000)/1000, math.Round(lon*1000)/1000 // let's three decimals precision
+		if lat != tc.lat || lon != tc.lon {
+			t.Fatalf("expected: (%g, %g), got: (%g, %g)", tc.lat, tc.lon, lat, lon)
+		}
+	}
+}
+
+func TestWebMercator(t *testing.T) {
+	tt := []struct {
+		lat, lon float64
+		x, y     float64
+	}{
+		{lat: 0, lon: 0, x: 0.5, y: 0.5},
+		{lat: 0, lon: -180, x: 0, y: 0.5},
+		{lat: 0, lon: 90, x: 0.75, y: 0.5},
+		{lat: geomath.MercatorMaxLatitude, lon: 0, x: 0.5, y: 0},
+		{lat: -90, lon: 0, x: 0.5, y: 1},
+		{lat: -7.2, lon: 109.9, x: 0.80528, y: 0.52005},
+	}
+
+	for _, tc := range tt {
+		x, y := geomath.WebMercator(tc.lat, tc.lon)
+		x, y = math.Round(x*100000)/100000, math.Round(y*100000)/100000 // let's five decimals precision
+		if x != tc.x || y != tc.y {
+			t.Fatalf("(%g, %g): expected: (%g, %g), got: (%g, %g)", tc.lat, tc.lon, tc.x, tc.y, x, y)
+		}
+	}
+}
+
+func TestDouglasPeucker(t *testing.T) {
+	tt := []struct {
+		name      string
+		lats      []float64
+		lons      []float64
+		tolerance float64
+		expected  []int
+	}{
+		{
+			name:      "empty",
+			expected:  []int{},
+			tolerance: 1,
+		},
+		{
+			name:      "straight line",
+			lats:      []float64{0, 0, 0, 0, 0},
+			lons:      []float64{0, 0.0001, 0.0002, 0.0003, 0.0004},
+			tolerance: 1,
+			expected:  []int{0, 4},
+		},
+		{
+			name:      "corner",
+			lats:      []float64{0, 0, 0, 0.0001, 0.0002},
+			lons:      []float64{0, 0.0001, 0.0002, 0.0002, 0.0002},
+			tolerance: 1,
+			expected:  []int{0, 2, 4},
+		},
+		{
+			name:      "small deviation below tolerance",
+			lats:      []float64{0, 0.00001, 0},
+			lons:      []float64{0, 0.0001, 0.0002},
+			tolerance: 5, // deviation is ~1.1m
+			expected:  []int{0, 2},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			indexes := geomath.DouglasPeucker(tc.lats, tc.lons, tc.tolerance)
+			if len(indexes) != len(tc.expected) {
+				t.Fatalf("expected: %v, got: %v", tc.expected, indexes)
+			}
+			for i := range indexes {
+				if indexes[i] != tc.expected[i] {
+					t.Fatalf("expected: %v, got: %v", tc.expected, indexes)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package kalman
+
+import "math"
+
+// mat2 is a 2x2 matrix in row-major order.
+type mat2 [4]float64
+
+func (m mat2) mul(n mat2) mat2 {
+	return mat2{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+	}
+}
+
+func (m mat2) transpose() mat2 { return mat2{m[0], m[2], m[1], m[3]} }
+
+func (m mat2) inverse() mat2 {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 {
+		return mat2{}
+	}
+	return mat2{m[3] / det, -m[1] / det, -m[2] / det, m[0] / det}
+}
+
+// Smooth smooths measurements zs taken at times ts (in seconds) using a constant-velocity Kalman filter
+// followed by Rauch-Tung-Striebel smoother, so every estimate uses both past and future measurements.
+// NaN measurements are treated as missing and estimated by the model. processNoise is the standard deviation
+// of the acceleration (unit/s²) and measurementNoise is the standard deviation of the measurement (unit).
+// The result has the same length as zs, values before the first valid measurement are NaN.
+//
+// ref: https://en.wikipedia.org/wiki/Kalman_filter#Rauch%E2%80%93Tung%E2%80%93Striebel
+func Smooth(ts, zs []float64, processNoise, measurementNoise float64) []float64 {
+	res := make([]float64, len(zs))
+	for i := range res {
+		res[i] = math.NaN()
+	}
+
+	start := -1
+	for i := range zs {
+		if !math.IsNaN(zs[i]) {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return res
+	}
+
+	var (
+		n  = len(zs)
+		q  = processNoise * processNoise
+		r  = measurementNoise * measurementNoise
+		xp = make([][2]float64, n) // predicted states
+		pp = make([]mat2, n)       // predicted covariances
+		xf = make([][2]float64, n) // filtered states
+		pf = make([]mat2, n)       // filtered covariances
+		fs = make([]mat2, n)       // state transitions
+	)
+
+	// Velocity is unknown at the beginning, let's start with big uncertainty.
+	xf[start] = [2]float64{zs[start], 0}
+	pf[start] = mat2{r, 0, 0, 1e4}
+	xp[start], pp[start] = xf[start], pf[start]
+
+	for k := start + 1; k < n; k++ {
+		dt := ts[k] - ts[k-1]
+		if dt < 0 {
+			dt = 0
+		}
+		f := mat2{1, dt, 0, 1}
+		fs[k] = f
+
+		// Predict
+		x := xf[k-1]
+		xp[k] = [2]float64{x[0] + dt*x[1], x[1]}
+		dt2, dt3 := dt*dt, dt*dt*dt
+		qm := mat2{q * dt3 / 3, q * dt2 / 2, q * dt2 / 2, q * dt}
+		p := f.mul(pf[k-1]).mul(f.transpose())
+		for i := range p {
+			p[i] += qm[i]
+		}
+		pp[k] = p
+
+		if math.IsNaN(zs[k]) {
+			xf[k], pf[k] = xp[k], pp[k]
+			continue
+		}
+
+		// Update
+		s := p[0] + r
+		k0, k1 := p[0]/s, p[2]/s
+		y := zs[k] - xp[k][0]
+		xf[k] = [2]float64{xp[k][0] + k0*y, xp[k][1] + k1*y}
+		pf[k] = mat2{
+			(1 - k0) * p[0], (1 - k0) * p[1],
+			p[2] - k1*p[0], p[3] - k1*p[1],
+		}
+	}
+
+	// Backward pass (RTS)
+	xs := xf[n-1]
+	res[n-1] = xs[0]
+	for k := n - 2; k >= start; k-- {
+		c := pf[k].mul(fs[k+1].transpose()).mul(pp[k+1].inverse())
+		d0, d1 := xs[0]-xp[k+1][0], xs[1]-xp[k+1][1]
+		xs = [2]float64{
+			xf[k][0] + c[0]*d0 + c[1]*d1,
+			xf[k][1] + c[2]*d0 + c[3]*d1,
+		}
+		res[k] = xs[0]
+	}
+
+	return res
+}
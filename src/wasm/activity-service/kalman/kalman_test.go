@@ -0,0 +1,96 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package kalman_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/openivity/activity-service/kalman"
+)
+
+func TestSmooth(t *testing.T) {
+	tt := []struct {
+		name string
+		ts   []float64
+		zs   []float64
+		exp  []float64
+	}{
+		{
+			name: "constant",
+			ts:   []float64{0, 1, 2, 3, 4},
+			zs:   []float64{10, 10, 10, 10, 10},
+			exp:  []float64{10, 10, 10, 10, 10},
+		},
+		{
+			name: "constant velocity",
+			ts:   []float64{0, 1, 2, 3, 4},
+			zs:   []float64{0, 5, 10, 15, 20},
+			exp:  []float64{0, 5, 10, 15, 20},
+		},
+		{
+			name: "missing measurements",
+			ts:   []float64{0, 1, 2, 3, 4},
+			zs:   []float64{math.NaN(), 5, math.NaN(), 15, 20},
+			exp:  []float64{math.NaN(), 5, 10, 15, 20},
+		},
+		{
+			name: "no measurements",
+			ts:   []float64{0, 1},
+			zs:   []float64{math.NaN(), math.NaN()},
+			exp:  []float64{math.NaN(), math.NaN()},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := kalman.Smooth(tc.ts, tc.zs, 1, 5)
+			for i := range res {
+				if math.IsNaN(tc.exp[i]) && math.IsNaN(res[i]) {
+					continue
+				}
+				if math.Abs(res[i]-tc.exp[i]) > 0.5 {
+					t.Fatalf("[%d] expected: %g, got: %g", i, tc.exp[i], res[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSmoothReduceNoise(t *testing.T) {
+	ts := make([]float64, 100)
+	zs := make([]float64, 100)
+	for i := range zs {
+		ts[i] = float64(i)
+		zs[i] = float64(i) * 3
+		if i%2 == 0 { // zig-zag noise
+			zs[i] += 4
+		} else {
+			zs[i] -= 4
+		}
+	}
+
+	res := kalman.Smooth(ts, zs, 0.5, 5)
+
+	var rawErr, smoothedErr float64
+	for i := range zs {
+		rawErr += math.Abs(zs[i] - float64(i)*3)
+		smoothedErr += math.Abs(res[i] - float64(i)*3)
+	}
+	if smoothedErr >= rawErr/2 {
+		t.Fatalf("expected smoothed error less than half of raw error: %g, got: %g", rawErr, smoothedErr)
+	}
+}
@@ -32,8 +32,10 @@ import (
 	"github.com/openivity/activity-service/activity/fit"
 	"github.com/openivity/activity-service/activity/gpx"
 	"github.com/openivity/activity-service/activity/tcx"
+	"github.com/openivity/activity-service/dem"
 	"github.com/openivity/activity-service/mem"
 	"github.com/openivity/activity-service/service"
+	"github.com/openivity/activity-service/service/result"
 	"github.com/openivity/activity-service/service/spec"
 	"github.com/openivity/activity-service/strutils"
 	"golang.org/x/exp/slices"
@@ -46,6 +48,18 @@ import (
 // is still considered EXPERIMENTAL. This is safe since every WebAssembly Instance is isolated.
 var decodedActivities = []activity.Activity{}
 
+// elevationModel is digital elevation model built from user's local DEM tiles, it's used by preprocessor
+// to correct records' altitude on decode.
+var elevationModel = &dem.Model{}
+
+// elevationFillOnly indicates that elevationModel is only used to fill records without altitude.
+var elevationFillOnly bool
+
+// preprocessSpec is the preprocessing settings of the current decode or encode request, requests without settings
+// use the default settings, except encode requests keep the athlete's profile of the previous request.
+// It's kept so loading elevation tiles can reconfigure the service with the same settings.
+var preprocessSpec spec.Preprocess
+
 //go:embed manufacturers.json
 var manufacturerJson []byte
 
@@ -53,6 +67,7 @@ func main() {
 	preproc := activity.NewPreprocessor()
 
 	svc := service.New(
+		preproc,
 		fit.NewDecodeEncoder(preproc),
 		gpx.NewDecodeEncoder(preproc),
 		tcx.NewDecodeEncoder(preproc),
@@ -61,8 +76,16 @@ func main() {
 
 	js.Global().Set("decode", createDecodeFunc(svc))
 	js.Global().Set("encode", createEncodeFunc(svc))
+	js.Global().Set("analyze", createAnalyzeFunc(svc))
 	js.Global().Set("manufacturerList", createManufacturerListFunc(svc))
 	js.Global().Set("sportList", createSportListFunc(svc))
+	js.Global().Set("loadElevationTiles", createLoadElevationTilesFunc(svc))
+	js.Global().Set("matchSegments", createMatchSegmentsFunc(svc))
+	js.Global().Set("encodeSegments", createEncodeSegmentsFunc())
+	js.Global().Set("compare", createCompareFunc(svc))
+	js.Global().Set("aggregate", createAggregateFunc(svc))
+	js.Global().Set("report", createReportFunc(svc))
+	js.Global().Set("exportRecords", createExportRecordsFunc())
 
 	// Add shutdown hook
 	quitc := make(chan struct{})
@@ -130,6 +153,17 @@ func createDecodeFunc(s *service.Service) js.Func {
 			return "{\"err\":\"no input is passed.\"}"
 		}
 
+		var settings spec.Preprocess
+		if len(args) > 1 && args[1].Type() == js.TypeString { // settings is an optional JSON string of spec.Preprocess
+			if err := json.Unmarshal([]byte(args[1].String()), &settings); err != nil {
+				return "{\"err\":\"could not unmarshal settings\"}"
+			}
+		}
+		preprocessSpec = settings
+		if err := configure(s); err != nil {
+			return fmt.Sprintf("{\"err\":%q}", err)
+		}
+
 		rs := make([]io.Reader, input.Length())
 
 		for i := 0; i < input.Length(); i++ {
@@ -166,6 +200,11 @@ func createEncodeFunc(svc *service.Service) js.Func {
 			return "{\"err\":\"could not unmarshal input\"}"
 		}
 
+		preprocessSpec = encodeSpec.PreprocessSettings(preprocessSpec.Athlete)
+		if err := configure(svc); err != nil {
+			return fmt.Sprintf("{\"err\":%q}", err)
+		}
+
 		encodeSpec.Activities = cloneActivities(decodedActivities)
 		elapsed := time.Since(begin)
 
@@ -181,6 +220,19 @@ func createEncodeFunc(svc *service.Service) js.Func {
 	})
 }
 
+func createAnalyzeFunc(svc *service.Service) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		result := svc.Analyze(context.Background(), decodedActivities)
+
+		buf := mem.GetBuffer()
+		defer mem.PutBuffer(buf)
+
+		b := result.MarshalAppendJSON(buf.Bytes())
+
+		return string(b)
+	})
+}
+
 func createManufacturerListFunc(svc *service.Service) js.Func {
 	return js.FuncOf(func(this js.Value, args []js.Value) any {
 		manufacturerList := svc.ManufacturerList()
@@ -197,6 +249,223 @@ func createSportListFunc(svc *service.Service) js.Func {
 	})
 }
 
+func createLoadElevationTilesFunc(svc *service.Service) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) < 2 {
+			return "{\"err\":\"no input is passed.\"}"
+		}
+		names := args[0] // names is an Array<string>, the tile's file names (e.g. N06E106.hgt)
+		tiles := args[1] // tiles is an Array<Uint8Array>
+		var fillOnly bool
+		if len(args) > 2 && args[2].Type() == js.TypeBoolean {
+			fillOnly = args[2].Bool() // only fill records without altitude
+		}
+
+		if names.Length() != tiles.Length() {
+			return "{\"err\":\"names and tiles length mismatch.\"}"
+		}
+
+		for i := 0; i < tiles.Length(); i++ {
+			b := make([]byte, tiles.Index(i).Length())
+			js.CopyBytesToGo(b, tiles.Index(i))
+
+			tile, err := dem.Decode(names.Index(i).String(), b)
+			if err != nil {
+				return fmt.Sprintf("{\"err\":%q}", fmt.Sprintf("tile[%d]: %v", i, err))
+			}
+			elevationModel.Add(tile)
+		}
+
+		elevationFillOnly = fillOnly
+		if err := configure(svc); err != nil {
+			return fmt.Sprintf("{\"err\":%q}", err)
+		}
+
+		return fmt.Sprintf("{\"err\":null,\"tiles\":%d}", elevationModel.Len())
+	})
+}
+
+func createMatchSegmentsFunc(svc *service.Service) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Length() == 0 {
+			return "{\"err\":\"no input is passed.\"}"
+		}
+		files := args[0] // files is an Array<Uint8Array> of segment library files (GPX)
+		var radius float64
+		if len(args) > 1 && args[1].Type() == js.TypeNumber {
+			radius = args[1].Float() // optional default radius in meters for segments not specifying their own
+		}
+
+		var segments []activity.Segment
+		for i := 0; i < files.Length(); i++ {
+			b := make([]byte, files.Index(i).Length())
+			js.CopyBytesToGo(b, files.Index(i))
+
+			decoded, err := gpx.DecodeSegments(bytes.NewReader(b))
+			if err != nil {
+				return fmt.Sprintf("{\"err\":%q}", fmt.Sprintf("file[%d]: %v", i, err))
+			}
+			segments = append(segments, decoded...)
+		}
+		for i := range segments {
+			if segments[i].Radius <= 0 {
+				segments[i].Radius = radius
+			}
+		}
+
+		result := svc.MatchSegments(context.Background(), decodedActivities, segments)
+
+		buf := mem.GetBuffer()
+		defer mem.PutBuffer(buf)
+
+		b := result.MarshalAppendJSON(buf.Bytes())
+
+		return string(b)
+	})
+}
+
+func createEncodeSegmentsFunc() js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return "{\"err\":\"no input is passed.\"}"
+		}
+
+		var specs []spec.Segment // input is a JSON string of []spec.Segment
+		if err := json.Unmarshal([]byte(args[0].String()), &specs); err != nil {
+			return "{\"err\":\"could not unmarshal input\"}"
+		}
+
+		segments := make([]activity.Segment, len(specs))
+		for i := range specs {
+			segments[i].Name = specs[i].Name
+			segments[i].Radius = specs[i].Radius
+			for _, p := range specs[i].Points {
+				segments[i].Lats = append(segments[i].Lats, p[0])
+				segments[i].Longs = append(segments[i].Longs, p[1])
+			}
+		}
+
+		b, err := gpx.EncodeSegments(segments)
+		if err != nil {
+			return fmt.Sprintf("{\"err\":%q}", err)
+		}
+
+		return fmt.Sprintf("{\"err\":null,\"file\":%s}", strconv.Quote(string(b)))
+	})
+}
+
+func createCompareFunc(svc *service.Service) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return "{\"err\":\"no input is passed.\"}"
+		}
+
+		var compareSpec spec.Compare // input is a JSON string of spec.Compare
+		if err := json.Unmarshal([]byte(args[0].String()), &compareSpec); err != nil {
+			return "{\"err\":\"could not unmarshal input\"}"
+		}
+
+		result := svc.Compare(context.Background(), decodedActivities, compareSpec)
+
+		buf := mem.GetBuffer()
+		defer mem.PutBuffer(buf)
+
+		b := result.MarshalAppendJSON(buf.Bytes())
+
+		return string(b)
+	})
+}
+
+func createAggregateFunc(svc *service.Service) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Length() == 0 {
+			return "{\"err\":\"no input is passed.\"}"
+		}
+		input := args[0] // input is an Array<Uint8Array>
+		zoom := uint8(activity.DefaultHeatmapZoom)
+		if len(args) > 1 && args[1].Type() == js.TypeNumber {
+			zoom = uint8(args[1].Int()) // optional heatmap's zoom level
+		}
+
+		if err := configure(svc); err != nil {
+			return fmt.Sprintf("{\"err\":%q}", err)
+		}
+
+		rs := make([]io.Reader, input.Length())
+		for i := 0; i < input.Length(); i++ {
+			b := make([]byte, input.Index(i).Length())
+			js.CopyBytesToGo(b, input.Index(i))
+			rs[i] = bytes.NewReader(b)
+		}
+
+		result := svc.Aggregate(context.Background(), rs, zoom)
+
+		buf := mem.GetBuffer()
+		defer mem.PutBuffer(buf)
+
+		b := result.MarshalAppendJSON(buf.Bytes())
+
+		return string(b)
+	})
+}
+
+func createReportFunc(svc *service.Service) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		var reportSpec spec.Report
+		if len(args) > 0 && args[0].Type() == js.TypeString { // input is an optional JSON string of spec.Report
+			if err := json.Unmarshal([]byte(args[0].String()), &reportSpec); err != nil {
+				return "{\"err\":\"could not unmarshal input\"}"
+			}
+		}
+
+		result := svc.Report(context.Background(), decodedActivities, reportSpec)
+
+		switch reportSpec.Format {
+		case "", spec.ReportFormatJSON:
+		case spec.ReportFormatCSV:
+			if result.Err != nil {
+				return fmt.Sprintf("{\"err\":%q}", result.Err)
+			}
+			buf := mem.GetBuffer()
+			defer mem.PutBuffer(buf)
+			if err := result.WriteCSV(buf); err != nil {
+				return fmt.Sprintf("{\"err\":%q}", err)
+			}
+			return fmt.Sprintf("{\"err\":null,\"file\":%s}", strconv.Quote(buf.String()))
+		default:
+			return fmt.Sprintf("{\"err\":%q}", fmt.Sprintf("report format %q is not supported", reportSpec.Format))
+		}
+
+		buf := mem.GetBuffer()
+		defer mem.PutBuffer(buf)
+
+		b := result.MarshalAppendJSON(buf.Bytes())
+
+		return string(b)
+	})
+}
+
+func createExportRecordsFunc() js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(decodedActivities) == 0 {
+			return "{\"err\":\"no activity is retrieved\"}"
+		}
+
+		buf := mem.GetBuffer()
+		defer mem.PutBuffer(buf)
+
+		if err := result.WriteRecordsCSV(buf, decodedActivities); err != nil {
+			return fmt.Sprintf("{\"err\":%q}", err)
+		}
+		return fmt.Sprintf("{\"err\":null,\"file\":%s}", strconv.Quote(buf.String()))
+	})
+}
+
+// configure configures the service's preprocessor using the latest preprocessing settings and elevation model.
+func configure(svc *service.Service) error {
+	return svc.Configure(preprocessSpec, activity.WithElevationModel(elevationModel, elevationFillOnly))
+}
+
 // cloneActivities clones activities so each encode invocation has isolated activities data.
 func cloneActivities(activities []activity.Activity) []activity.Activity {
 	activities = slices.Clone(activities)
@@ -219,6 +488,13 @@ func cloneActivities(activities []activity.Activity) []activity.Activity {
 				laps[k].Lap = &base
 			}
 			sessions[j].Laps = laps
+
+			lengths := slices.Clone(sessions[j].Lengths)
+			for k := range lengths {
+				base := *lengths[k].Length
+				lengths[k].Length = &base
+			}
+			sessions[j].Lengths = lengths
 		}
 		activities[i].Sessions = sessions
 	}
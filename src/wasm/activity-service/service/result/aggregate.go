@@ -0,0 +1,121 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package result
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openivity/activity-service/activity"
+)
+
+// Aggregate is aggregate result.
+type Aggregate struct {
+	Err           error
+	AggregateTook time.Duration
+	Activities    int                // Number of aggregated activities.
+	Heatmap       *activity.Heatmap  // Positions of all activities.
+	SportTotals   []SportTotal       // Sorted by sport name.
+	Failures      []AggregateFailure // Files that could not be decoded, sorted by index.
+}
+
+// AggregateFailure is a file that could not be decoded, it's skipped from the aggregation.
+type AggregateFailure struct {
+	Index int // Index of the file in the input.
+	Err   error
+}
+
+// SportTotal is the total of the sessions of a sport.
+type SportTotal struct {
+	Sport       string
+	Activities  int     // Number of activities having at least one session of the sport.
+	Sessions    int     // Number of sessions of the sport.
+	Distance    float64 // in meters
+	ElapsedTime float64 // in seconds
+	MovingTime  float64 // in seconds
+	Ascent      float64 // in meters
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Aggregate to b, returning the result.
+func (a *Aggregate) MarshalAppendJSON(b []byte) []byte {
+	if a.Err != nil {
+		return []byte(fmt.Sprintf("{%q:%q}", "err", a.Err))
+	}
+
+	b = append(b, '{')
+	b = append(b, `"err":null,`...)
+
+	b = append(b, `"activities":`...)
+	b = strconv.AppendInt(b, int64(a.Activities), 10)
+	b = append(b, ',')
+
+	if a.Heatmap != nil {
+		b = append(b, `"heatmap":`...)
+		b = a.Heatmap.MarshalAppendJSON(b)
+		b = append(b, ',')
+	}
+
+	b = append(b, `"sportTotals":[`...)
+	for i := range a.SportTotals {
+		b = a.SportTotals[i].MarshalAppendJSON(b)
+		if i != len(a.SportTotals)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"failures":[`...)
+	for i := range a.Failures {
+		b = append(b, `{"index":`...)
+		b = strconv.AppendInt(b, int64(a.Failures[i].Index), 10)
+		b = append(b, `,"err":`...)
+		b = strconv.AppendQuote(b, a.Failures[i].Err.Error())
+		b = append(b, '}')
+		if i != len(a.Failures)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"aggregateTook":`...)
+	b = append(b, strconv.FormatInt(a.AggregateTook.Milliseconds(), 10)...)
+
+	b = append(b, '}')
+
+	return b
+}
+
+// MarshalAppendJSON appends the JSON format encoding of SportTotal to b, returning the result.
+func (s *SportTotal) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"sport":`...)
+	b = strconv.AppendQuote(b, s.Sport)
+	b = append(b, `,"activities":`...)
+	b = strconv.AppendInt(b, int64(s.Activities), 10)
+	b = append(b, `,"sessions":`...)
+	b = strconv.AppendInt(b, int64(s.Sessions), 10)
+	b = append(b, `,"distance":`...)
+	b = strconv.AppendFloat(b, s.Distance, 'f', 1, 64)
+	b = append(b, `,"elapsedTime":`...)
+	b = strconv.AppendFloat(b, s.ElapsedTime, 'f', 0, 64)
+	b = append(b, `,"movingTime":`...)
+	b = strconv.AppendFloat(b, s.MovingTime, 'f', 0, 64)
+	b = append(b, `,"ascent":`...)
+	b = strconv.AppendFloat(b, s.Ascent, 'f', 0, 64)
+	return append(b, '}')
+}
@@ -0,0 +1,149 @@
+// Copyright (C) 2023 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package result
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/openivity/activity-service/activity"
+)
+
+// Analyze is analyze result.
+type Analyze struct {
+	Err         error
+	AnalyzeTook time.Duration
+	Sessions    []Analysis // Analysis of each session across activities.
+	Overall     Analysis   // Analysis across all sessions, e.g. season curve.
+
+	ActivityLoads []ActivityLoad               // Training load of each activity.
+	TrainingLoads []activity.DailyTrainingLoad // Daily training load covering all activities' dates.
+}
+
+// ActivityLoad is the training load of an activity.
+type ActivityLoad struct {
+	StartTime time.Time
+	Method    string  // Either "tss", "trimp" or "mixed" if the sessions use different methods, empty if not available.
+	Load      float64 // NaN if not available.
+}
+
+// Analysis is the mean-maximal curves and best efforts of one or more sessions.
+type Analysis struct {
+	MeanMaxPower     activity.MeanMax
+	MeanMaxHeartRate activity.MeanMax
+	BestEfforts      []activity.BestEffort
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Analyze to b, returning the result.
+func (a *Analyze) MarshalAppendJSON(b []byte) []byte {
+	if a.Err != nil {
+		return []byte(fmt.Sprintf("{%q:%q}", "err", a.Err))
+	}
+
+	b = append(b, '{')
+	b = append(b, `"err":null,`...)
+
+	b = append(b, `"sessions":[`...)
+	for i := range a.Sessions {
+		b = a.Sessions[i].MarshalAppendJSON(b)
+		if i != len(a.Sessions)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"overall":`...)
+	b = a.Overall.MarshalAppendJSON(b)
+	b = append(b, ',')
+
+	b = append(b, `"activityLoads":[`...)
+	for i := range a.ActivityLoads {
+		b = a.ActivityLoads[i].MarshalAppendJSON(b)
+		if i != len(a.ActivityLoads)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"trainingLoads":[`...)
+	for i := range a.TrainingLoads {
+		b = a.TrainingLoads[i].MarshalAppendJSON(b)
+		if i != len(a.TrainingLoads)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"analyzeTook":`...)
+	b = append(b, strconv.FormatInt(a.AnalyzeTook.Milliseconds(), 10)...)
+
+	b = append(b, '}')
+
+	return b
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Analysis to b, returning the result.
+func (a *Analysis) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '{')
+
+	b = append(b, `"meanMaxPower":`...)
+	b = a.MeanMaxPower.MarshalAppendJSON(b)
+	b = append(b, ',')
+
+	b = append(b, `"meanMaxHeartRate":`...)
+	b = a.MeanMaxHeartRate.MarshalAppendJSON(b)
+	b = append(b, ',')
+
+	b = append(b, `"bestEfforts":[`...)
+	for i := range a.BestEfforts {
+		b = a.BestEfforts[i].MarshalAppendJSON(b)
+		if i != len(a.BestEfforts)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+
+	b = append(b, '}')
+
+	return b
+}
+
+// MarshalAppendJSON appends the JSON format encoding of ActivityLoad to b, returning the result.
+func (a *ActivityLoad) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '{')
+	if !a.StartTime.IsZero() {
+		b = append(b, `"startTime":`...)
+		b = strconv.AppendQuote(b, a.StartTime.Format(time.RFC3339))
+		b = append(b, ',')
+	}
+	if a.Method != "" && !math.IsNaN(a.Load) {
+		b = append(b, `"method":`...)
+		b = strconv.AppendQuote(b, a.Method)
+		b = append(b, ',')
+		b = append(b, `"load":`...)
+		b = strconv.AppendFloat(b, a.Load, 'f', 1, 64)
+		b = append(b, ',')
+	}
+	if b[len(b)-1] == ',' {
+		b = b[:len(b)-1]
+	}
+	return append(b, '}')
+}
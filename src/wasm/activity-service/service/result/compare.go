@@ -0,0 +1,68 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package result
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openivity/activity-service/activity"
+	"github.com/openivity/activity-service/service/spec"
+)
+
+// Compare is compare result.
+type Compare struct {
+	Err         error
+	CompareTook time.Duration
+	Sessions    []spec.CompareSession // The compared sessions, matching Comparison's Attempts.
+	Comparison  activity.Comparison
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Compare to b, returning the result.
+func (c *Compare) MarshalAppendJSON(b []byte) []byte {
+	if c.Err != nil {
+		return []byte(fmt.Sprintf("{%q:%q}", "err", c.Err))
+	}
+
+	b = append(b, '{')
+	b = append(b, `"err":null,`...)
+
+	b = append(b, `"sessions":[`...)
+	for i := range c.Sessions {
+		b = append(b, `{"activityIndex":`...)
+		b = strconv.AppendInt(b, int64(c.Sessions[i].ActivityIndex), 10)
+		b = append(b, `,"sessionIndex":`...)
+		b = strconv.AppendInt(b, int64(c.Sessions[i].SessionIndex), 10)
+		b = append(b, '}')
+		if i != len(c.Sessions)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"comparison":`...)
+	b = c.Comparison.MarshalAppendJSON(b)
+	b = append(b, ',')
+
+	b = append(b, `"compareTook":`...)
+	b = append(b, strconv.FormatInt(c.CompareTook.Milliseconds(), 10)...)
+
+	b = append(b, '}')
+
+	return b
+}
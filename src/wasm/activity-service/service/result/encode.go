@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"strconv"
 	"time"
+
+	"github.com/openivity/activity-service/activity"
 )
 
 // Encode is encode result.
@@ -31,6 +33,7 @@ type Encode struct {
 	FileName             string
 	FileType             string
 	FilesBytes           [][]byte
+	FilledGaps           [][]activity.FilledGap // Gaps filled with interpolated records of each file, nil if gaps are not filled.
 }
 
 // MarshalAppendJSON appends the JSON format encoding of Encode to b, returning the result.
@@ -75,6 +78,24 @@ func (e *Encode) MarshalAppendJSON(b []byte) []byte {
 	}
 	b = append(b, `],`...)
 
+	if e.FilledGaps != nil {
+		b = append(b, `"filledGaps":[`...)
+		for i := range e.FilledGaps {
+			b = append(b, '[')
+			for j := range e.FilledGaps[i] {
+				b = e.FilledGaps[i][j].MarshalAppendJSON(b)
+				if j != len(e.FilledGaps[i])-1 {
+					b = append(b, ',')
+				}
+			}
+			b = append(b, ']')
+			if i != len(e.FilledGaps)-1 {
+				b = append(b, ',')
+			}
+		}
+		b = append(b, `],`...)
+	}
+
 	e.SerializationTook = time.Since(begin)
 	e.TotalElapsed = e.DeserializeInputTook + e.EncodeTook + e.SerializationTook
 
@@ -0,0 +1,102 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package result
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/openivity/activity-service/activity"
+)
+
+// WriteRecordsCSV writes the records of every activity's sessions in CSV format to w, a row for each record
+// including the values calculated by us such as grade, pace and W' balance. Empty cells mean not available.
+func WriteRecordsCSV(w io.Writer, activities []activity.Activity) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"activity", "session", "timestamp", "position_lat", "position_long",
+		"distance", "altitude", "speed", "heart_rate", "cadence", "power",
+		"grade", "pace", "grade_adjusted_pace", "w_prime_balance", "interpolated",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	row := make([]string, len(header))
+	for i := range activities {
+		for j := range activities[i].Sessions {
+			ses := &activities[i].Sessions[j]
+			for k := range ses.Records {
+				rec := &ses.Records[k]
+
+				var timestamp string
+				if !rec.Timestamp.IsZero() {
+					timestamp = rec.Timestamp.Format(time.RFC3339)
+				}
+				lat, long := math.NaN(), math.NaN()
+				if rec.PositionLat != basetype.Sint32Invalid && rec.PositionLong != basetype.Sint32Invalid {
+					lat, long = rec.PositionLatDegrees(), rec.PositionLongDegrees()
+				}
+				altitude := rec.EnhancedAltitudeScaled()
+				if math.IsNaN(altitude) {
+					altitude = rec.AltitudeScaled()
+				}
+				speed := rec.EnhancedSpeedScaled()
+				if math.IsNaN(speed) {
+					speed = rec.SpeedScaled()
+				}
+
+				row = row[:0]
+				row = append(row,
+					strconv.Itoa(i),
+					strconv.Itoa(j),
+					timestamp,
+					formatCSVFloat(lat, 7),
+					formatCSVFloat(long, 7),
+					formatCSVFloat(rec.DistanceScaled(), 2),
+					formatCSVFloat(altitude, 1),
+					formatCSVFloat(speed, 3),
+					formatCSVUint(uint64(rec.HeartRate), rec.HeartRate == basetype.Uint8Invalid),
+					formatCSVUint(uint64(rec.Cadence), rec.Cadence == basetype.Uint8Invalid),
+					formatCSVUint(uint64(rec.Power), rec.Power == basetype.Uint16Invalid),
+					formatCSVFloat(rec.Grade, 2),
+					formatCSVFloat(rec.Pace, 1),
+					formatCSVFloat(rec.GradeAdjustedPace, 1),
+					formatCSVFloat(rec.WPrimeBalance, 0),
+					strconv.FormatBool(rec.Interpolated),
+				)
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCSVUint(v uint64, invalid bool) string {
+	if invalid {
+		return ""
+	}
+	return strconv.FormatUint(v, 10)
+}
@@ -0,0 +1,215 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package result
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Report is report result.
+type Report struct {
+	Err        error
+	ReportTook time.Duration
+	Period     string        // Either "week" or "month".
+	Sports     []ReportGroup // Summary of each sport across all activities, sorted by sport.
+	Periods    []ReportGroup // Summary of each sport in each period, sorted by period then sport.
+}
+
+// ReportGroup is the summary of sessions of a sport. Fields are named following aggregator's prefix convention,
+// so groups can be merged using aggregator.Aggregate except AvgHeartRate and AvgPower which are weighted by time.
+type ReportGroup struct {
+	Sport  string
+	Period time.Time // Start of the week (Monday) or the month in activity's local time, zero if not grouped by period.
+
+	NumSessions      uint32
+	TotalDistance    float64 // in meters, NaN if not available.
+	TotalMovingTime  float64 // in seconds, NaN if not available.
+	TotalElapsedTime float64 // in seconds, NaN if not available.
+	TotalAscent      float64 // in meters, NaN if not available.
+	TotalCalories    float64 // in kcal, NaN if not available.
+	AvgHeartRate     float64 // in bpm weighted by session's moving time, NaN if not available.
+	AvgPower         float64 // in watts weighted by session's moving time, NaN if not available.
+	MaxDistance      float64 // The longest session's distance in meters, NaN if not available.
+	MaxAvgSpeed      float64 // The fastest session's average speed in m/s, NaN if not available.
+}
+
+// NewReportGroup creates new ReportGroup with all values set to NaN.
+func NewReportGroup(sport string, period time.Time) ReportGroup {
+	return ReportGroup{
+		Sport:            sport,
+		Period:           period,
+		TotalDistance:    math.NaN(),
+		TotalMovingTime:  math.NaN(),
+		TotalElapsedTime: math.NaN(),
+		TotalAscent:      math.NaN(),
+		TotalCalories:    math.NaN(),
+		AvgHeartRate:     math.NaN(),
+		AvgPower:         math.NaN(),
+		MaxDistance:      math.NaN(),
+		MaxAvgSpeed:      math.NaN(),
+	}
+}
+
+// MarshalAppendJSON appends the JSON format encoding of Report to b, returning the result.
+func (r *Report) MarshalAppendJSON(b []byte) []byte {
+	if r.Err != nil {
+		return []byte(fmt.Sprintf("{%q:%q}", "err", r.Err))
+	}
+
+	b = append(b, '{')
+	b = append(b, `"err":null,`...)
+
+	b = append(b, `"period":`...)
+	b = strconv.AppendQuote(b, r.Period)
+	b = append(b, ',')
+
+	b = append(b, `"sports":[`...)
+	for i := range r.Sports {
+		b = r.Sports[i].MarshalAppendJSON(b)
+		if i != len(r.Sports)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"periods":[`...)
+	for i := range r.Periods {
+		b = r.Periods[i].MarshalAppendJSON(b)
+		if i != len(r.Periods)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"reportTook":`...)
+	b = append(b, strconv.FormatInt(r.ReportTook.Milliseconds(), 10)...)
+
+	b = append(b, '}')
+
+	return b
+}
+
+// WriteCSV writes Report in CSV format to w, a row for each sport followed by a row for each period's sport.
+// Empty cells mean not available.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"group", "period", "sport", "sessions",
+		"distance", "moving_time", "elapsed_time", "ascent", "calories",
+		"avg_heart_rate", "avg_power", "longest_distance", "fastest_avg_speed",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	row := make([]string, len(header))
+	for _, groups := range [][]ReportGroup{r.Sports, r.Periods} {
+		for i := range groups {
+			g := &groups[i]
+			row = row[:0]
+			if g.Period.IsZero() {
+				row = append(row, "sport", "")
+			} else {
+				row = append(row, r.Period, g.Period.Format(time.DateOnly))
+			}
+			row = append(row,
+				g.Sport,
+				strconv.FormatUint(uint64(g.NumSessions), 10),
+				formatCSVFloat(g.TotalDistance, 1),
+				formatCSVFloat(g.TotalMovingTime, 0),
+				formatCSVFloat(g.TotalElapsedTime, 0),
+				formatCSVFloat(g.TotalAscent, 0),
+				formatCSVFloat(g.TotalCalories, 0),
+				formatCSVFloat(g.AvgHeartRate, 0),
+				formatCSVFloat(g.AvgPower, 0),
+				formatCSVFloat(g.MaxDistance, 1),
+				formatCSVFloat(g.MaxAvgSpeed, 2),
+			)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCSVFloat(v float64, prec int) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', prec, 64)
+}
+
+// MarshalAppendJSON appends the JSON format encoding of ReportGroup to b, returning the result.
+// Values that are not available are omitted.
+func (g *ReportGroup) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, '{')
+
+	b = append(b, `"sport":`...)
+	b = strconv.AppendQuote(b, g.Sport)
+	b = append(b, ',')
+
+	if !g.Period.IsZero() {
+		b = append(b, `"period":`...)
+		b = strconv.AppendQuote(b, g.Period.Format(time.DateOnly))
+		b = append(b, ',')
+	}
+
+	b = append(b, `"sessions":`...)
+	b = strconv.AppendUint(b, uint64(g.NumSessions), 10)
+	b = append(b, ',')
+
+	fields := [...]struct {
+		name  string
+		value float64
+		prec  int
+	}{
+		{name: "totalDistance", value: g.TotalDistance, prec: 1},
+		{name: "totalMovingTime", value: g.TotalMovingTime, prec: 0},
+		{name: "totalElapsedTime", value: g.TotalElapsedTime, prec: 0},
+		{name: "totalAscent", value: g.TotalAscent, prec: 0},
+		{name: "totalCalories", value: g.TotalCalories, prec: 0},
+		{name: "avgHeartRate", value: g.AvgHeartRate, prec: 0},
+		{name: "avgPower", value: g.AvgPower, prec: 0},
+		{name: "longestDistance", value: g.MaxDistance, prec: 1},
+		{name: "fastestAvgSpeed", value: g.MaxAvgSpeed, prec: 2},
+	}
+	for _, f := range fields {
+		if math.IsNaN(f.value) {
+			continue
+		}
+		b = append(b, '"')
+		b = append(b, f.name...)
+		b = append(b, `":`...)
+		b = strconv.AppendFloat(b, f.value, 'f', f.prec, 64)
+		b = append(b, ',')
+	}
+
+	if b[len(b)-1] == ',' {
+		b = b[:len(b)-1]
+	}
+	return append(b, '}')
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package result
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openivity/activity-service/activity"
+)
+
+// MatchSegments is match segments result.
+type MatchSegments struct {
+	Err               error
+	MatchSegmentsTook time.Duration
+	Segments          []SegmentMatch // Matches of each segment in the same order as the given segments.
+}
+
+// SegmentMatch is every pass of a segment across activities.
+type SegmentMatch struct {
+	Name    string
+	Length  float64         // in meters.
+	Efforts []SegmentEffort // Sorted by elapsed time, the fastest first.
+}
+
+// SegmentEffort is a pass of a segment in an activity's session.
+type SegmentEffort struct {
+	ActivityIndex int
+	SessionIndex  int
+	activity.SegmentEffort
+}
+
+// MarshalAppendJSON appends the JSON format encoding of MatchSegments to b, returning the result.
+func (m *MatchSegments) MarshalAppendJSON(b []byte) []byte {
+	if m.Err != nil {
+		return []byte(fmt.Sprintf("{%q:%q}", "err", m.Err))
+	}
+
+	b = append(b, '{')
+	b = append(b, `"err":null,`...)
+
+	b = append(b, `"segments":[`...)
+	for i := range m.Segments {
+		b = m.Segments[i].MarshalAppendJSON(b)
+		if i != len(m.Segments)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	b = append(b, ',')
+
+	b = append(b, `"matchSegmentsTook":`...)
+	b = append(b, strconv.FormatInt(m.MatchSegmentsTook.Milliseconds(), 10)...)
+
+	b = append(b, '}')
+
+	return b
+}
+
+// MarshalAppendJSON appends the JSON format encoding of SegmentMatch to b, returning the result.
+func (s *SegmentMatch) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"name":`...)
+	b = strconv.AppendQuote(b, s.Name)
+	b = append(b, `,"length":`...)
+	b = strconv.AppendFloat(b, s.Length, 'f', 1, 64)
+	b = append(b, `,"efforts":[`...)
+	for i := range s.Efforts {
+		b = s.Efforts[i].MarshalAppendJSON(b)
+		if i != len(s.Efforts)-1 {
+			b = append(b, ',')
+		}
+	}
+	b = append(b, ']')
+	return append(b, '}')
+}
+
+// MarshalAppendJSON appends the JSON format encoding of SegmentEffort to b, returning the result.
+func (s *SegmentEffort) MarshalAppendJSON(b []byte) []byte {
+	b = append(b, `{"activityIndex":`...)
+	b = strconv.AppendInt(b, int64(s.ActivityIndex), 10)
+	b = append(b, `,"sessionIndex":`...)
+	b = strconv.AppendInt(b, int64(s.SessionIndex), 10)
+	b = append(b, ',')
+	n := len(b)
+	b = s.SegmentEffort.MarshalAppendJSON(b)
+	return append(b[:n], b[n+1:]...) // Merge the effort's fields by removing its opening brace.
+}
@@ -16,18 +16,25 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/muktihari/fit/factory"
+	"github.com/muktihari/fit/kit/datetime"
 	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
 	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/fieldnum"
 	"github.com/muktihari/fit/profile/untyped/mesgnum"
+	"github.com/muktihari/fit/proto"
 	"github.com/openivity/activity-service/activity"
 	"github.com/openivity/activity-service/aggregator"
 	"github.com/openivity/activity-service/service/result"
@@ -48,22 +55,162 @@ type DecodeEncoder interface {
 
 // Service is an activity service. It handle decoding and encoding these following file formats: FIT, GPX and TCX.
 type Service struct {
-	fit           DecodeEncoder
-	gpx           DecodeEncoder
-	tcx           DecodeEncoder
-	manufacturers map[typedef.Manufacturer]activity.Manufacturer
+	preprocessor    *activity.Preprocessor
+	athlete         spec.Athlete
+	heartRateZones  []float64
+	splitLength     float64 // in meters
+	minStopDuration time.Duration
+	fit             DecodeEncoder
+	gpx             DecodeEncoder
+	tcx             DecodeEncoder
+	manufacturers   map[typedef.Manufacturer]activity.Manufacturer
 }
 
 // New creates new activity service to handle decoding and encoding these following file formats: FIT, GPX and TCX.
-func New(fit, gpx, tcx DecodeEncoder, manufacturers map[typedef.Manufacturer]activity.Manufacturer) *Service {
+// The preprocessor should be the same preprocessor used by the DecodeEncoders so it can be configured per request.
+func New(preprocessor *activity.Preprocessor, fit, gpx, tcx DecodeEncoder, manufacturers map[typedef.Manufacturer]activity.Manufacturer) *Service {
 	return &Service{
-		fit:           fit,
-		gpx:           gpx,
-		tcx:           tcx,
-		manufacturers: manufacturers,
+		preprocessor:    preprocessor,
+		minStopDuration: activity.DefaultMinStopDuration,
+		fit:             fit,
+		gpx:             gpx,
+		tcx:             tcx,
+		manufacturers:   manufacturers,
 	}
 }
 
+// Configure resets the preprocessor using given preprocessing settings, additional opts will be applied afterward.
+// It should be called before Decode or Encode, and not while either of them is running.
+func (s *Service) Configure(preprocessSpec spec.Preprocess, opts ...activity.Option) error {
+	var disabledStages activity.Stage
+	for _, v := range preprocessSpec.DisabledStages {
+		switch v {
+		case spec.PreprocessStageRemoveGPSOutliers:
+			disabledStages |= activity.StageRemoveGPSOutliers
+		case spec.PreprocessStageCorrectElevation:
+			disabledStages |= activity.StageCorrectElevation
+		case spec.PreprocessStageSmoothingElevation:
+			disabledStages |= activity.StageSmoothingElevation
+		case spec.PreprocessStageCalculateGrade:
+			disabledStages |= activity.StageCalculateGrade
+		default:
+			return fmt.Errorf("preprocess stage '%s' not recognized", v)
+		}
+	}
+
+	var toleranceMovingSpeeds map[typedef.Sport]float64
+	if len(preprocessSpec.ToleranceMovingSpeeds) != 0 {
+		toleranceMovingSpeeds = make(map[typedef.Sport]float64, len(preprocessSpec.ToleranceMovingSpeeds))
+		for name, speed := range preprocessSpec.ToleranceMovingSpeeds {
+			sport := typedef.SportFromString(strutils.ToLowerSnakeCase(name))
+			if sport == typedef.SportInvalid {
+				return fmt.Errorf("sport '%s' not recognized", name)
+			}
+			if speed < 0 {
+				return fmt.Errorf("tolerance moving speed of '%s' should not be negative", name)
+			}
+			toleranceMovingSpeeds[sport] = speed
+		}
+	}
+
+	heartRateZones, err := s.heartRateZoneBoundaries(preprocessSpec.Athlete)
+	if err != nil {
+		return err
+	}
+
+	var splitLength float64
+	switch preprocessSpec.SplitUnit {
+	case "", spec.SplitUnitKilometer:
+		splitLength = activity.SplitLengthKilometer
+	case spec.SplitUnitMile:
+		splitLength = activity.SplitLengthMile
+	default:
+		return fmt.Errorf("split unit '%s' not recognized", preprocessSpec.SplitUnit)
+	}
+	if preprocessSpec.SplitLength < 0 {
+		return fmt.Errorf("split length should not be negative")
+	}
+	if preprocessSpec.SplitLength > 0 {
+		splitLength *= preprocessSpec.SplitLength
+	}
+
+	minStopDuration := activity.DefaultMinStopDuration
+	if preprocessSpec.MinStopDuration > 0 {
+		minStopDuration = time.Duration(preprocessSpec.MinStopDuration) * time.Second
+	}
+
+	var elevationGain activity.ElevationGain
+	switch preprocessSpec.ElevationGain {
+	case "", spec.ElevationGainSmoothed:
+		elevationGain = activity.ElevationGainSmoothed{}
+	case spec.ElevationGainRaw:
+		elevationGain = activity.ElevationGainRaw{}
+	case spec.ElevationGainHysteresis:
+		elevationGain = activity.ElevationGainHysteresis{Threshold: preprocessSpec.ElevationGainThreshold}
+	default:
+		return fmt.Errorf("elevation gain '%s' not recognized", preprocessSpec.ElevationGain)
+	}
+
+	options := []activity.Option{
+		activity.WithSmoothingElevationDistance(preprocessSpec.SmoothingElevationDistance),
+		activity.WithCalculateDistance(preprocessSpec.CalculateGradeDistance),
+		activity.WithMaxAcceleration(preprocessSpec.MaxAcceleration),
+		activity.WithSmoothingPosition(preprocessSpec.SmoothingPosition),
+		activity.WithSmoothingPositionNoise(preprocessSpec.ProcessNoise, preprocessSpec.MeasurementNoise),
+		activity.WithDisabledStages(disabledStages),
+		activity.WithToleranceMovingSpeeds(toleranceMovingSpeeds),
+		activity.WithElevationGain(elevationGain),
+	}
+
+	s.preprocessor.Reset(append(options, opts...)...)
+	s.athlete = preprocessSpec.Athlete
+	s.heartRateZones = heartRateZones
+	s.splitLength = splitLength
+	s.minStopDuration = minStopDuration
+
+	return nil
+}
+
+// heartRateZoneBoundaries returns heart rate zones' high boundaries from athlete's profile, nil if not specified.
+func (s *Service) heartRateZoneBoundaries(athlete spec.Athlete) ([]float64, error) {
+	var method activity.HeartRateZoneMethod
+	switch athlete.HeartRateZoneMethod {
+	case "":
+		if len(athlete.HeartRateZones) == 0 {
+			return nil, nil
+		}
+		method = activity.HeartRateZoneCustom
+	case spec.HeartRateZoneCustom:
+		method = activity.HeartRateZoneCustom
+	case spec.HeartRateZoneMaxHeartRate:
+		method = activity.HeartRateZoneMaxHeartRate
+	case spec.HeartRateZoneThreshold:
+		method = activity.HeartRateZoneThreshold
+	case spec.HeartRateZoneReserve:
+		method = activity.HeartRateZoneReserve
+	default:
+		return nil, fmt.Errorf("heart rate zone method '%s' not recognized", athlete.HeartRateZoneMethod)
+	}
+
+	if method != activity.HeartRateZoneCustom {
+		boundaries := activity.HeartRateZoneBoundaries(method,
+			athlete.MaxHeartRate, athlete.RestingHeartRate, athlete.ThresholdHeartRate)
+		if boundaries == nil {
+			return nil, fmt.Errorf("heart rate zone method '%s': required heart rate is not specified", athlete.HeartRateZoneMethod)
+		}
+		return boundaries, nil
+	}
+
+	boundaries := make([]float64, len(athlete.HeartRateZones))
+	for i, v := range athlete.HeartRateZones {
+		if i > 0 && v <= athlete.HeartRateZones[i-1] {
+			return nil, fmt.Errorf("heart rate zones should be in ascending order")
+		}
+		boundaries[i] = float64(v)
+	}
+	return boundaries, nil
+}
+
 func (s *Service) Decode(ctx context.Context, rs []io.Reader) result.Decode {
 	begin := time.Now()
 
@@ -129,12 +276,180 @@ func (s *Service) Decode(ctx context.Context, rs []io.Reader) result.Decode {
 		return 0
 	})
 
+	for i := range activities {
+		s.analyze(&activities[i])
+	}
+
 	return result.Decode{
 		DecodeTook: time.Since(begin),
 		Activities: activities,
 	}
 }
 
+// analyze calculates analytic data of the activity's sessions and laps based on the configured athlete's profile.
+func (s *Service) analyze(a *activity.Activity) {
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+
+		ses.TimeInHeartRateZone = activity.NewTimeInHeartRateZone(ses.Records, s.heartRateZones)
+		s.fillSessionPowerMetrics(ses)
+		activity.CalculateWPrimeBalance(ses.Records, float64(s.athlete.CriticalPower), float64(s.athlete.WPrime))
+		ses.Splits = activity.NewSplits(ses.Records, ses.Sport, s.splitLength, s.preprocessor.Summarizer())
+		ses.Climbs = activity.DetectClimbs(ses.Records)
+		ses.Stops = activity.DetectStops(ses.Records, ses.Sport, s.minStopDuration, s.preprocessor.Summarizer())
+		if efficiency, ok := activity.NewEfficiency(ses.Records, ses.Sport, s.preprocessor.Summarizer()); ok {
+			ses.Efficiency = &efficiency
+		}
+		if activity.HasPace(ses.Sport) {
+			if pace := activity.AvgGradeAdjustedPace(ses.Records); !math.IsNaN(pace) {
+				ses.AvgGradeAdjustedPace = pace
+			}
+		}
+		for j := range ses.Laps {
+			lap := &ses.Laps[j]
+			records := lapRecords(lap, ses.Records)
+			lap.TimeInHeartRateZone = activity.NewTimeInHeartRateZone(records, s.heartRateZones)
+			s.fillLapPowerMetrics(lap, records, ses.Sport)
+			lap.MinWPrimeBalance = activity.MinWPrimeBalance(records)
+			if activity.HasPace(ses.Sport) {
+				if pace := activity.AvgGradeAdjustedPace(records); !math.IsNaN(pace) {
+					lap.AvgGradeAdjustedPace = pace
+				}
+			}
+		}
+	}
+
+	s.fillCalories(a)
+}
+
+// fillCalories estimates sessions' and laps' calories that are not recorded by the device based on the athlete's
+// profile. The estimation is kept in EstimatedCalories, so it's recalculated whenever the profile is changed.
+func (s *Service) fillCalories(a *activity.Activity) {
+	profile := activity.CalorieProfile{
+		Weight: s.athlete.Weight,
+		Age:    s.athlete.Age,
+		Female: s.athlete.Gender == spec.GenderFemale,
+	}
+	estimate := func(records []activity.Record, sport typedef.Sport) float64 {
+		kcal := activity.EstimateCalories(records, sport, profile, s.preprocessor.Summarizer())
+		if math.IsNaN(kcal) {
+			return 0
+		}
+		return kcal
+	}
+
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		ses.EstimatedCalories = 0
+		if ses.TotalCalories == basetype.Uint16Invalid {
+			ses.EstimatedCalories = estimate(ses.Records, ses.Sport)
+		}
+		for j := range ses.Laps {
+			lap := &ses.Laps[j]
+			lap.EstimatedCalories = 0
+			if lap.TotalCalories == basetype.Uint16Invalid {
+				lap.EstimatedCalories = estimate(lapRecords(lap, ses.Records), ses.Sport)
+			}
+		}
+	}
+}
+
+// writeEstimatedCalories writes sessions' and laps' estimated calories as their total calories, since the file
+// has no way to tell the estimation apart. It should only be used on activities that are about to be encoded.
+func writeEstimatedCalories(a *activity.Activity) {
+	toTotalCalories := func(kcal float64) uint16 {
+		return uint16(math.Min(math.Round(kcal), float64(basetype.Uint16Invalid-1)))
+	}
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		if ses.TotalCalories == basetype.Uint16Invalid && ses.EstimatedCalories > 0 {
+			ses.TotalCalories = toTotalCalories(ses.EstimatedCalories)
+		}
+		for j := range ses.Laps {
+			lap := &ses.Laps[j]
+			if lap.TotalCalories == basetype.Uint16Invalid && lap.EstimatedCalories > 0 {
+				lap.TotalCalories = toTotalCalories(lap.EstimatedCalories)
+			}
+		}
+	}
+}
+
+// fillPowerMetrics fills sessions' and laps' power metrics that are not recorded by the device.
+func (s *Service) fillPowerMetrics(a *activity.Activity) {
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		s.fillSessionPowerMetrics(ses)
+		for j := range ses.Laps {
+			lap := &ses.Laps[j]
+			s.fillLapPowerMetrics(lap, lapRecords(lap, ses.Records), ses.Sport)
+		}
+	}
+}
+
+// fillSessionPowerMetrics fills cycling session's power metrics (NP, IF, TSS and total work) that are not recorded
+// by the device. IF and TSS are only calculated when athlete's FTP is specified.
+func (s *Service) fillSessionPowerMetrics(ses *activity.Session) {
+	if ses.Sport != typedef.SportCycling {
+		return
+	}
+	pm, ok := activity.NewPowerMetrics(ses.Records)
+	if !ok {
+		return
+	}
+
+	ftp := float64(s.athlete.FunctionalThresholdPower)
+	if ses.NormalizedPower == basetype.Uint16Invalid && !math.IsNaN(pm.NormalizedPower) {
+		ses.NormalizedPower = uint16(math.Round(pm.NormalizedPower))
+	}
+	if ses.TotalWork == basetype.Uint32Invalid {
+		ses.TotalWork = uint32(math.Round(pm.Work))
+	}
+	if ses.ThresholdPower == basetype.Uint16Invalid && ftp > 0 {
+		ses.ThresholdPower = s.athlete.FunctionalThresholdPower
+	}
+	if ses.IntensityFactor == basetype.Uint16Invalid && ftp > 0 && !math.IsNaN(pm.NormalizedPower) {
+		ses.SetIntensityFactorScaled(pm.IntensityFactor(ftp))
+	}
+	if ses.TrainingStressScore == basetype.Uint16Invalid && ftp > 0 && !math.IsNaN(pm.NormalizedPower) {
+		ses.SetTrainingStressScoreScaled(pm.TrainingStressScore(ftp))
+	}
+	if !math.IsNaN(pm.VariabilityIndex) {
+		ses.VariabilityIndex = pm.VariabilityIndex
+	}
+}
+
+// fillLapPowerMetrics fills cycling lap's power metrics (NP and total work) that are not recorded by the device.
+func (s *Service) fillLapPowerMetrics(lap *activity.Lap, records []activity.Record, sport typedef.Sport) {
+	if sport != typedef.SportCycling {
+		return
+	}
+	pm, ok := activity.NewPowerMetrics(records)
+	if !ok {
+		return
+	}
+
+	if lap.NormalizedPower == basetype.Uint16Invalid && !math.IsNaN(pm.NormalizedPower) {
+		lap.NormalizedPower = uint16(math.Round(pm.NormalizedPower))
+	}
+	if lap.TotalWork == basetype.Uint32Invalid {
+		lap.TotalWork = uint32(math.Round(pm.Work))
+	}
+	if !math.IsNaN(pm.VariabilityIndex) {
+		lap.VariabilityIndex = pm.VariabilityIndex
+	}
+}
+
+// lapRecords returns records belong to the given lap's time window.
+func lapRecords(lap *activity.Lap, records []activity.Record) []activity.Record {
+	lapRecords := make([]activity.Record, 0)
+	for i := range records {
+		if lap.IsBelongToThisLap(records[i].Timestamp) {
+			lapRecords = append(lapRecords, records[i])
+		}
+	}
+	return lapRecords
+}
+
 func firstNonZeroTimestamp(act *activity.Activity) time.Time {
 	for i := range act.Sessions {
 		for j := range act.Sessions[i].Records {
@@ -213,7 +528,7 @@ func (s *Service) creatorName(manufacturerID typedef.Manufacturer, productID uin
 func (s *Service) Encode(ctx context.Context, encodeSpec spec.Encode) result.Encode {
 	begin := time.Now()
 
-	activities, err := s.preprocessEncode(encodeSpec)
+	activities, filledGaps, err := s.preprocessEncode(encodeSpec)
 	if err != nil {
 		return result.Encode{Err: err}
 	}
@@ -234,24 +549,27 @@ func (s *Service) Encode(ctx context.Context, encodeSpec spec.Encode) result.Enc
 		FileName:   fmt.Sprintf("openivity-%d-%s", begin.Unix(), encodeSpec.ToolMode),
 		FileType:   encodeSpec.TargetFileType.String(),
 		FilesBytes: bs,
+		FilledGaps: filledGaps,
 		Err:        err,
 		EncodeTook: time.Since(begin),
 	}
 }
 
-func (s *Service) preprocessEncode(encodeSpec spec.Encode) ([]activity.Activity, error) {
+// preprocessEncode preprocesses the activities according to encodeSpec, it returns the activities to be encoded as
+// files and the gaps filled in each of those files, if any.
+func (s *Service) preprocessEncode(encodeSpec spec.Encode) ([]activity.Activity, [][]activity.FilledGap, error) {
 	if encodeSpec.ToolMode == spec.ToolModeUnknown {
-		return nil, fmt.Errorf("encode mode '%v' not recognized", encodeSpec.ToolMode)
+		return nil, nil, fmt.Errorf("encode mode '%v' not recognized", encodeSpec.ToolMode)
 	}
 
 	activities := encodeSpec.Activities
 	if len(activities) == 0 {
-		return nil, fmt.Errorf("no activity is retrieved")
+		return nil, nil, fmt.Errorf("no activity is retrieved")
 	}
 
 	if encodeSpec.TargetFileType == spec.FileTypeFIT {
 		if _, ok := s.manufacturers[encodeSpec.ManufacturerID]; !ok {
-			return nil, fmt.Errorf("manufacturer %d does not exist", encodeSpec.ManufacturerID)
+			return nil, nil, fmt.Errorf("manufacturer %d does not exist", encodeSpec.ManufacturerID)
 		}
 	}
 
@@ -260,17 +578,39 @@ func (s *Service) preprocessEncode(encodeSpec spec.Encode) ([]activity.Activity,
 		removeFields[v] = struct{}{}
 	}
 
+	fillGapsThreshold := time.Duration(encodeSpec.FillGapsThreshold) * time.Second
+	if fillGapsThreshold == 0 {
+		fillGapsThreshold = activity.DefaultFillGapsThreshold
+	}
+
 	// Preprocess data before encoding
 	var validActivityCount int
+	var sessionFilledGaps [][][]activity.FilledGap // per activity per session.
 	for i := range activities {
 		activity := &activities[i]
 		n := len(activities[i].Sessions) + i // markers is based on session across activities.
 
+		if encodeSpec.Preprocess != nil {
+			s.reprocess(activity)
+		}
+		if encodeSpec.UseSmoothed {
+			s.useSmoothed(activity)
+		}
 		if err := s.concealGPSPositions(activity, encodeSpec.ConcealMarkers[i:n]); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		var trimMarkers []spec.EncodeMarker
+		if encodeSpec.AutoTrim {
+			trimMarkers = s.autoTrimMarkers(activity)
+		} else {
+			trimMarkers = encodeSpec.TrimMarkers[i:n]
 		}
-		if err := s.trimRecords(activity, encodeSpec.TrimMarkers[i:n]); err != nil {
-			return nil, err
+		if err := s.trimRecords(activity, trimMarkers); err != nil {
+			return nil, nil, err
+		}
+		if encodeSpec.FillGaps != spec.GapFillNone {
+			sessionFilledGaps = append(sessionFilledGaps,
+				s.fillGaps(activity, fillGapsThreshold, encodeSpec.FillGaps == spec.GapFillHold))
 		}
 
 		if len(activity.Sessions) == 0 {
@@ -283,7 +623,7 @@ func (s *Service) preprocessEncode(encodeSpec spec.Encode) ([]activity.Activity,
 	}
 
 	if validActivityCount == 0 {
-		return nil, fmt.Errorf("no activity data after processed")
+		return nil, nil, fmt.Errorf("no activity data after processed")
 	}
 
 	var newActivities []activity.Activity
@@ -300,9 +640,69 @@ func (s *Service) preprocessEncode(encodeSpec spec.Encode) ([]activity.Activity,
 		newActivities = []activity.Activity{newActivity}
 	case spec.ToolModeSplitPerSession:
 		newActivities = s.splitActivityPerSession(activities, encodeSpec.ManufacturerID, encodeSpec.ProductID)
+	case spec.ToolModeRemovePauses:
+		for i := range activities {
+			s.removePauses(&activities[i], encodeSpec.PauseRemoval)
+			activities[i].Creator.Manufacturer = encodeSpec.ManufacturerID
+			activities[i].Creator.Product = encodeSpec.ProductID
+			activities[i].Creator.Name = encodeSpec.DeviceName
+		}
+		newActivities = activities
+	}
+
+	// Trimming, combining and pause removal recalculate sessions and laps from records, fill power metrics
+	// and re-estimate calories using the current athlete's profile.
+	for i := range newActivities {
+		s.fillPowerMetrics(&newActivities[i])
+		s.fillCalories(&newActivities[i])
+		writeEstimatedCalories(&newActivities[i])
+	}
+
+	if encodeSpec.WriteSplits && encodeSpec.TargetFileType == spec.FileTypeFIT {
+		for i := range newActivities {
+			s.writeSplits(&newActivities[i])
+		}
 	}
 
-	return newActivities, nil
+	// Simplify last, everything above needs the full record stream to produce correct summaries.
+	for i := range newActivities {
+		s.simplifyRecords(&newActivities[i], encodeSpec)
+	}
+
+	var filledGaps [][]activity.FilledGap
+	if encodeSpec.FillGaps != spec.GapFillNone {
+		filledGaps = filledGapsPerFile(encodeSpec.ToolMode, sessionFilledGaps)
+	}
+
+	return newActivities, filledGaps, nil
+}
+
+// filledGapsPerFile groups the filled gaps of every activity's sessions by the files produced by the tool mode.
+func filledGapsPerFile(toolMode spec.EncodeToolMode, sessionFilledGaps [][][]activity.FilledGap) [][]activity.FilledGap {
+	var filledGaps [][]activity.FilledGap
+	switch toolMode {
+	case spec.ToolModeCombine:
+		var gaps []activity.FilledGap
+		for i := range sessionFilledGaps {
+			for j := range sessionFilledGaps[i] {
+				gaps = append(gaps, sessionFilledGaps[i][j]...)
+			}
+		}
+		filledGaps = append(filledGaps, gaps)
+	case spec.ToolModeSplitPerSession:
+		for i := range sessionFilledGaps {
+			filledGaps = append(filledGaps, sessionFilledGaps[i]...)
+		}
+	default:
+		for i := range sessionFilledGaps {
+			var gaps []activity.FilledGap
+			for j := range sessionFilledGaps[i] {
+				gaps = append(gaps, sessionFilledGaps[i][j]...)
+			}
+			filledGaps = append(filledGaps, gaps)
+		}
+	}
+	return filledGaps
 }
 
 func (s *Service) combineActivity(activities []activity.Activity, manufacturer typedef.Manufacturer, product uint16) activity.Activity {
@@ -354,13 +754,14 @@ func (s *Service) combineActivity(activities []activity.Activity, manufacturer t
 			// Combine records and laps to newActivity's last session
 			newActLastSes.Records = append(newActLastSes.Records, curActFirstSes.Records...)
 			newActLastSes.Laps = append(newActLastSes.Laps, curActFirstSes.Laps...)
+			newActLastSes.Lengths = append(newActLastSes.Lengths, curActFirstSes.Lengths...)
 
 			// Update summary
 			gap := (curActFirstSes.StartTime.Sub(newActLastSes.EndTime()).Seconds() * 1000)
 			newActLastSes.TotalElapsedTime += uint32(gap)
 			newActLastSes.TotalTimerTime += uint32(gap)
 			aggregator.Aggregate(newActLastSes.Session, curActFirstSes.Session)
-			newActLastSes.Summarize()
+			newActLastSes.Summarize(s.preprocessor.Summarizer())
 
 			cur.Sessions = cur.Sessions[1:]
 		}
@@ -499,6 +900,7 @@ func (s *Service) trimRecords(a *activity.Activity, markers []spec.EncodeMarker)
 
 		if marker.StartN == marker.EndN {
 			ses.Records = nil
+			ses.Lengths = nil
 			continue
 		}
 
@@ -525,49 +927,407 @@ func (s *Service) trimRecords(a *activity.Activity, markers []spec.EncodeMarker)
 			continue
 		}
 
-		records := slices.Clone(ses.Records)
+		ses.TrimLengths(ses.Records[0].Timestamp, ses.Records[len(ses.Records)-1].Timestamp)
+		s.recalculateSummaryFromRecords(ses)
+	}
 
-		// Recalculate Lap and Session Summary
-		newLaps := make([]activity.Lap, 0)
-		for i := range ses.Laps {
-			lap := &ses.Laps[i]
+	// Validate Records in Sessions, pool swimming sessions may only have lengths.
+	validSessions := make([]activity.Session, 0, len(a.Sessions))
+	for i := range a.Sessions {
+		if len(a.Sessions[i].Records) == 0 && len(a.Sessions[i].Lengths) == 0 {
+			continue
+		}
+		validSessions = append(validSessions, a.Sessions[i])
+	}
 
-			var pos int
-			for j := range records {
-				rec := &records[j]
-				if lap.IsBelongToThisLap(rec.Timestamp) {
-					records[j], records[pos] = records[pos], records[j]
-					pos++
-				}
+	a.Sessions = validSessions
+
+	return nil
+}
+
+// autoTrimMarkers creates trim markers that remove the stationary head and tail of each session's records.
+// Sessions that are never moving are left untouched.
+func (s *Service) autoTrimMarkers(a *activity.Activity) []spec.EncodeMarker {
+	markers := make([]spec.EncodeMarker, len(a.Sessions))
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		markers[i] = spec.EncodeMarker{StartN: 0, EndN: len(ses.Records) - 1}
+		if start, end, ok := activity.MovingRange(ses.Records, ses.Sport, s.preprocessor.Summarizer()); ok {
+			markers[i] = spec.EncodeMarker{StartN: start, EndN: end}
+		}
+	}
+	return markers
+}
+
+// recalculateSummaryFromRecords recreates session's laps and session's summary from its records,
+// records are grouped into laps using the existing laps' time windows. Session should have at least 1 record.
+func (s *Service) recalculateSummaryFromRecords(ses *activity.Session) {
+	records := slices.Clone(ses.Records)
+
+	newLaps := make([]activity.Lap, 0)
+	for i := range ses.Laps {
+		lap := &ses.Laps[i]
+
+		var pos int
+		for j := range records {
+			rec := &records[j]
+			if lap.IsBelongToThisLap(rec.Timestamp) {
+				records[j], records[pos] = records[pos], records[j]
+				pos++
 			}
-			lapRecords := records[:pos]
+		}
+		lapRecords := records[:pos]
+
+		if len(lapRecords) != 0 {
+			lapFromRecords := activity.NewLapFromRecords(lapRecords, ses.Sport, s.preprocessor.Summarizer())
+			newLaps = append(newLaps, lapFromRecords)
+		}
+		records = records[pos:]
+	}
+
+	newSes := activity.NewSessionFromLaps(newLaps)
+	newSes.Laps = newLaps
+	newSes.Records = ses.Records
+	newSes.PoolLength, newSes.PoolLengthUnit = ses.PoolLength, ses.PoolLengthUnit
+	newSes.Lengths = ses.Lengths
+	newSes.Summarize(s.preprocessor.Summarizer())
+	*ses = newSes
+}
+
+// removePauses removes stationary periods from the activity using given method so the timer time equals moving time.
+func (s *Service) removePauses(a *activity.Activity, method spec.PauseRemovalMethod) {
+	recorded := timerPauses(a) // Collect before inserting any event.
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+
+		pauses := activity.DetectPauses(ses.Records, ses.Sport, s.preprocessor.Summarizer())
+		if len(pauses) == 0 {
+			continue
+		}
+
+		switch method {
+		case spec.PauseRemovalTimerEvents:
+			s.insertTimerEvents(a, ses, pauses, recorded)
+		default:
+			s.compressPauses(ses, pauses)
+		}
+	}
+}
 
-			if len(lapRecords) != 0 {
-				lapFromRecords := activity.NewLapFromRecords(lapRecords, ses.Sport)
-				newLaps = append(newLaps, lapFromRecords)
+// compressPauses removes stationary records and shifts later timestamps back, so elapsed time equals moving time.
+func (s *Service) compressPauses(ses *activity.Session, pauses []activity.Pause) {
+	// shiftAt returns how much t should be shifted back, t within a pause will be moved to the pause's start.
+	shiftAt := func(t time.Time) time.Duration {
+		var shift time.Duration
+		for i := range pauses {
+			p := &pauses[i]
+			if !t.After(p.StartTime) {
+				break
+			}
+			if t.Before(p.EndTime) {
+				return shift + t.Sub(p.StartTime)
 			}
-			records = records[pos:]
+			shift += p.Duration()
 		}
+		return shift
+	}
 
-		newSes := activity.NewSessionFromLaps(newLaps)
-		newSes.Laps = newLaps
-		newSes.Records = ses.Records
-		newSes.Summarize()
-		*ses = newSes
+	for i := range ses.Laps {
+		lap := &ses.Laps[i]
+		endTime := lap.EndTime()
+		lap.StartTime = lap.StartTime.Add(-shiftAt(lap.StartTime))
+		if !endTime.IsZero() {
+			endTime = endTime.Add(-shiftAt(endTime))
+			lap.TotalElapsedTime = uint32(endTime.Sub(lap.StartTime).Milliseconds())
+		}
 	}
 
-	// Validate Records in Sessions
-	validSessions := make([]activity.Session, 0, len(a.Sessions))
+	for i := range ses.Lengths {
+		length := &ses.Lengths[i]
+		length.StartTime = length.StartTime.Add(-shiftAt(length.StartTime))
+		if !length.Timestamp.IsZero() {
+			length.Timestamp = length.Timestamp.Add(-shiftAt(length.Timestamp))
+		}
+	}
+
+	records := make([]activity.Record, 0, len(ses.Records))
+	var cur int
+	for i := range ses.Records {
+		if cur < len(pauses) && i >= pauses[cur].EndIndex {
+			cur++
+		}
+		if cur < len(pauses) && i >= pauses[cur].StartIndex && i < pauses[cur].EndIndex {
+			continue // Stationary record
+		}
+		rec := ses.Records[i]
+		if !rec.Timestamp.IsZero() {
+			rec.Timestamp = rec.Timestamp.Add(-shiftAt(rec.Timestamp))
+		}
+		records = append(records, rec)
+	}
+
+	if len(records) == 0 {
+		return
+	}
+
+	startTime := ses.StartTime
+	ses.Records = records
+	s.recalculateSummaryFromRecords(ses)
+	ses.StartTime = startTime.Add(-shiftAt(startTime))
+	ses.Summarize(s.preprocessor.Summarizer())
+}
+
+// insertTimerEvents keeps the records as it is and inserts timer stop/start events for every pause, except pauses
+// overlapping the recorded ones since the device's timer is already stopped there.
+// Timer events are only retained in FIT files, while other formats only retain the updated TotalTimerTime.
+func (s *Service) insertTimerEvents(a *activity.Activity, ses *activity.Session, pauses, recorded []activity.Pause) {
+	isRecorded := func(p *activity.Pause) bool {
+		for i := range recorded {
+			if p.StartTime.Before(recorded[i].EndTime) && recorded[i].StartTime.Before(p.EndTime) {
+				return true
+			}
+		}
+		return false
+	}
+
+	stopped := append([]activity.Pause(nil), recorded...)
+	for i := range pauses {
+		p := &pauses[i]
+		if isRecorded(p) {
+			continue
+		}
+		stopped = append(stopped, *p)
+
+		stop := mesgdef.NewEvent(nil).
+			SetTimestamp(p.StartTime).
+			SetEvent(typedef.EventTimer).
+			SetEventType(typedef.EventTypeStopAll).
+			SetEventGroup(0)
+		a.UnrelatedMessages = append(a.UnrelatedMessages, stop.ToMesg(nil))
+
+		if p.EndIndex == len(ses.Records) {
+			continue // Never resumed
+		}
+		start := mesgdef.NewEvent(nil).
+			SetTimestamp(p.EndTime).
+			SetEvent(typedef.EventTimer).
+			SetEventType(typedef.EventTypeStart).
+			SetEventGroup(0)
+		a.UnrelatedMessages = append(a.UnrelatedMessages, start.ToMesg(nil))
+	}
+
+	for i := range ses.Laps {
+		lap := &ses.Laps[i]
+		if lap.TotalElapsedTime == basetype.Uint32Invalid {
+			continue
+		}
+		paused := activity.PausedDuration(stopped, lap.StartTime, lap.EndTime())
+		lap.TotalTimerTime = lap.TotalElapsedTime - min(uint32(paused.Milliseconds()), lap.TotalElapsedTime)
+	}
+
+	if ses.TotalElapsedTime != basetype.Uint32Invalid {
+		paused := activity.PausedDuration(stopped, ses.StartTime, ses.EndTime())
+		ses.TotalTimerTime = ses.TotalElapsedTime - min(uint32(paused.Milliseconds()), ses.TotalElapsedTime)
+	}
+}
+
+// reprocess recalculates smoothed altitude, grade and the summary that depend on them (moving time, ascent and descent)
+// using current preprocessor's settings. Positions and distance are not reprocessed since the raw data is no longer available.
+func (s *Service) reprocess(a *activity.Activity) {
+	sm := s.preprocessor.Summarizer()
 	for i := range a.Sessions {
-		if len(a.Sessions[i].Records) == 0 {
+		ses := &a.Sessions[i]
+
+		s.preprocessor.SmoothingElevation(ses.Records)
+		s.preprocessor.CalculateGrade(ses.Records)
+		if activity.HasPace(ses.Sport) {
+			s.preprocessor.CalculateGradeAdjustedPace(ses.Records)
+		}
+
+		for j := range ses.Laps {
+			lap := &ses.Laps[j]
+
+			records := lapRecords(lap, ses.Records)
+			lap.TotalMovingTime = sm.TotalMovingTime(records, ses.Sport)
+			lap.TotalAscent, lap.TotalDescent = sm.TotalAscentAndDescent(records)
+		}
+
+		ses.TotalMovingTime = sm.TotalMovingTime(ses.Records, ses.Sport)
+		ses.TotalAscent, ses.TotalDescent = sm.TotalAscentAndDescent(ses.Records)
+		ses.ElevationGainMethod = sm.ElevationGainAlgorithm().Name()
+	}
+}
+
+// writeSplits recalculates distance-based splits of each session and writes them as FIT split messages and
+// a split summary, replacing any existing splits with the same split type.
+func (s *Service) writeSplits(a *activity.Activity) {
+	const splitType = typedef.SplitTypeIntervalOther
+
+	// Build new slices rather than filtering in place, the activity may share its backing arrays with other copies.
+	unrelatedMessages := make([]proto.Message, 0, len(a.UnrelatedMessages))
+	for _, mesg := range a.UnrelatedMessages {
+		if mesg.Num == mesgnum.Split && mesg.FieldValueByNum(fieldnum.SplitSplitType).Uint8() == uint8(splitType) {
 			continue
 		}
-		validSessions = append(validSessions, a.Sessions[i])
+		unrelatedMessages = append(unrelatedMessages, mesg)
 	}
+	a.UnrelatedMessages = unrelatedMessages
 
-	a.Sessions = validSessions
+	splitSummaries := make([]*mesgdef.SplitSummary, 0, len(a.SplitSummaries))
+	for _, splitSummary := range a.SplitSummaries {
+		if splitSummary.SplitType == splitType {
+			continue
+		}
+		splitSummaries = append(splitSummaries, splitSummary)
+	}
+	a.SplitSummaries = splitSummaries
 
-	return nil
+	summary := mesgdef.NewSplitSummary(nil).
+		SetSplitType(splitType).
+		SetMessageIndex(typedef.MessageIndex(len(a.SplitSummaries)))
+
+	var numSplits uint16
+	var totalTimerTime, totalMovingTime, totalDistance float64
+	var totalAscent, totalDescent float64
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		ses.Splits = activity.NewSplits(ses.Records, ses.Sport, s.splitLength, s.preprocessor.Summarizer())
+
+		for j := range ses.Splits {
+			split := &ses.Splits[j]
+			mesgSplit := mesgdef.NewSplit(nil).
+				SetMessageIndex(typedef.MessageIndex(numSplits)).
+				SetSplitType(splitType).
+				SetStartTime(split.StartTime).
+				SetEndTime(split.EndTime).
+				SetTotalElapsedTimeScaled(split.ElapsedTime.Seconds()).
+				SetTotalTimerTimeScaled(split.ElapsedTime.Seconds()).
+				SetTotalMovingTimeScaled(split.MovingTime.Seconds()).
+				SetTotalDistanceScaled(split.Distance).
+				SetTotalAscent(uint16(math.Round(split.Ascent))).
+				SetTotalDescent(uint16(math.Round(split.Descent))).
+				SetStartPositionLat(split.StartPositionLat).
+				SetStartPositionLong(split.StartPositionLong).
+				SetEndPositionLat(split.EndPositionLat).
+				SetEndPositionLong(split.EndPositionLong)
+			if split.ElapsedTime > 0 {
+				mesgSplit.SetAvgSpeedScaled(split.Distance / split.ElapsedTime.Seconds())
+			}
+
+			// Split does not have timestamp, add one so it will be placed right after its last record when
+			// the messages are sorted. It's marked as ExpandedField so it will be removed after sorting.
+			mesg := mesgSplit.ToMesg(nil)
+			timestampField := factory.CreateField(mesgnum.Split, proto.FieldNumTimestamp).
+				WithValue(datetime.ToUint32(split.EndTime))
+			timestampField.IsExpandedField = true
+			mesg.Fields = append(mesg.Fields, timestampField)
+			a.UnrelatedMessages = append(a.UnrelatedMessages, mesg)
+
+			numSplits++
+			totalTimerTime += split.ElapsedTime.Seconds()
+			totalMovingTime += split.MovingTime.Seconds()
+			totalDistance += split.Distance
+			totalAscent += split.Ascent
+			totalDescent += split.Descent
+		}
+	}
+
+	if numSplits == 0 {
+		return
+	}
+
+	summary.
+		SetNumSplits(numSplits).
+		SetTotalTimerTimeScaled(totalTimerTime).
+		SetTotalMovingTimeScaled(totalMovingTime).
+		SetTotalDistanceScaled(totalDistance).
+		SetTotalAscent(uint16(math.Round(totalAscent))).
+		SetTotalDescent(uint16(math.Round(totalDescent)))
+	if totalTimerTime > 0 {
+		summary.SetAvgSpeedScaled(totalDistance / totalTimerTime)
+	}
+
+	a.SplitSummaries = append(a.SplitSummaries, summary)
+}
+
+// useSmoothed replaces records' positions and altitude with the smoothed ones produced by the preprocessor.
+func (s *Service) useSmoothed(a *activity.Activity) {
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		for j := range ses.Records {
+			rec := &ses.Records[j]
+			if rec.SmoothedPositionLat != basetype.Sint32Invalid && rec.SmoothedPositionLong != basetype.Sint32Invalid {
+				rec.PositionLat = rec.SmoothedPositionLat
+				rec.PositionLong = rec.SmoothedPositionLong
+			}
+			if !math.IsNaN(rec.SmoothedAltitude) {
+				if rec.Altitude != basetype.Uint16Invalid {
+					rec.SetAltitudeScaled(rec.SmoothedAltitude)
+				}
+				if rec.EnhancedAltitude != basetype.Uint32Invalid {
+					rec.SetEnhancedAltitudeScaled(rec.SmoothedAltitude)
+				}
+			}
+		}
+	}
+}
+
+// simplifyRecords reduces the number of records using specified method. Sessions and laps summary are retained
+// since the records are only a representation of the original data, hence this must be the last step of preprocessing.
+func (s *Service) simplifyRecords(a *activity.Activity, encodeSpec spec.Encode) {
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		switch encodeSpec.Simplify {
+		case spec.SimplifyDouglasPeucker:
+			ses.Records = activity.SimplifyRecords(ses.Records, encodeSpec.Tolerance)
+		case spec.SimplifyResample:
+			ses.Records = activity.ResampleRecords(ses.Records, time.Duration(encodeSpec.Interval)*time.Second)
+		}
+	}
+}
+
+// fillGaps inserts interpolated records into the gaps of 1s record stream and recalculate the summary.
+// It returns the filled gaps of each session.
+func (s *Service) fillGaps(a *activity.Activity, threshold time.Duration, holdSensors bool) [][]activity.FilledGap {
+	pauses := timerPauses(a)
+	filledGaps := make([][]activity.FilledGap, len(a.Sessions))
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+		n := len(ses.Records)
+		ses.Records = activity.FillGaps(ses.Records, time.Second, threshold, holdSensors, pauses)
+		if len(ses.Records) != n {
+			s.recalculateSummaryFromRecords(ses)
+			filledGaps[i] = activity.FilledGaps(ses.Records)
+		}
+	}
+	return filledGaps
+}
+
+// timerPauses returns time windows where the device's timer is stopped based on timer events.
+func timerPauses(a *activity.Activity) []activity.Pause {
+	var pauses []activity.Pause
+	var stoppedAt time.Time
+	for i := range a.UnrelatedMessages {
+		if a.UnrelatedMessages[i].Num != mesgnum.Event {
+			continue
+		}
+		event := mesgdef.NewEvent(&a.UnrelatedMessages[i])
+		if event.Event != typedef.EventTimer || event.Timestamp.IsZero() {
+			continue
+		}
+		switch event.EventType {
+		case typedef.EventTypeStop, typedef.EventTypeStopAll:
+			if stoppedAt.IsZero() {
+				stoppedAt = event.Timestamp
+			}
+		case typedef.EventTypeStart:
+			if !stoppedAt.IsZero() {
+				pauses = append(pauses, activity.Pause{StartTime: stoppedAt, EndTime: event.Timestamp})
+				stoppedAt = time.Time{}
+			}
+		}
+	}
+	return pauses
 }
 
 // concealGPSPositions conceal positions from the records by removing PositionLat and PositionLong.
@@ -711,6 +1471,424 @@ func (s *Service) removeFields(a *activity.Activity, fields map[string]struct{})
 	}
 }
 
+// Analyze analyzes the given activities, returning mean-maximal power and heart rate curves and best efforts
+// of each session as well as across all sessions.
+func (s *Service) Analyze(ctx context.Context, activities []activity.Activity) result.Analyze {
+	begin := time.Now()
+
+	if len(activities) == 0 {
+		return result.Analyze{Err: fmt.Errorf("no activity is retrieved")}
+	}
+
+	res := result.Analyze{
+		Overall: result.Analysis{
+			BestEfforts: activity.NewBestEfforts(nil, activity.BestEffortDistances),
+		},
+	}
+	for i := range activities {
+		for j := range activities[i].Sessions {
+			if err := ctx.Err(); err != nil {
+				return result.Analyze{Err: err}
+			}
+
+			records := activities[i].Sessions[j].Records
+			analysis := result.Analysis{
+				MeanMaxPower:     activity.NewMeanMaxPower(records, activity.MeanMaxDurations),
+				MeanMaxHeartRate: activity.NewMeanMaxHeartRate(records, activity.MeanMaxDurations),
+				BestEfforts:      activity.NewBestEfforts(records, activity.BestEffortDistances),
+			}
+
+			res.Overall.MeanMaxPower.Merge(analysis.MeanMaxPower)
+			res.Overall.MeanMaxHeartRate.Merge(analysis.MeanMaxHeartRate)
+			for k := range analysis.BestEfforts {
+				best, cur := &res.Overall.BestEfforts[k], &analysis.BestEfforts[k]
+				if cur.Duration != 0 && (best.Duration == 0 || cur.Duration < best.Duration) {
+					*best = *cur
+				}
+			}
+
+			res.Sessions = append(res.Sessions, analysis)
+		}
+
+		res.ActivityLoads = append(res.ActivityLoads, s.activityLoad(&activities[i]))
+	}
+
+	dates := make([]time.Time, 0, len(res.ActivityLoads))
+	loads := make([]float64, 0, len(res.ActivityLoads))
+	for i := range res.ActivityLoads {
+		if res.ActivityLoads[i].StartTime.IsZero() {
+			continue
+		}
+		// Shift to the activity's local time so the load is attributed to the correct day.
+		dates = append(dates, res.ActivityLoads[i].StartTime.Add(time.Duration(activities[i].Timezone)*time.Hour))
+		loads = append(loads, res.ActivityLoads[i].Load)
+	}
+	res.TrainingLoads = activity.NewDailyTrainingLoads(dates, loads)
+
+	res.AnalyzeTook = time.Since(begin)
+
+	return res
+}
+
+// activityLoad calculates the training load of the activity: session's TSS if any (either recorded by the device or
+// calculated from the athlete's FTP), otherwise TRIMP from the athlete's resting and max heart rate.
+func (s *Service) activityLoad(a *activity.Activity) result.ActivityLoad {
+	load := result.ActivityLoad{Load: math.NaN()}
+
+	startTime := firstNonZeroTimestamp(a)
+	if startTime.IsZero() {
+		startTime = a.Creator.TimeCreated
+	}
+	load.StartTime = startTime
+
+	female := s.athlete.Gender == spec.GenderFemale
+	for i := range a.Sessions {
+		ses := &a.Sessions[i]
+
+		var method string
+		value := math.NaN()
+		if ses.TrainingStressScore != basetype.Uint16Invalid {
+			method, value = "tss", ses.TrainingStressScoreScaled()
+		} else if trimp := activity.TRIMP(ses.Records,
+			s.athlete.RestingHeartRate, s.athlete.MaxHeartRate, female); !math.IsNaN(trimp) {
+			method, value = "trimp", trimp
+		}
+		if method == "" {
+			continue
+		}
+
+		switch load.Method {
+		case "":
+			load.Method, load.Load = method, value
+		case method:
+			load.Load += value
+		default:
+			load.Method = "mixed"
+			load.Load += value
+		}
+	}
+
+	return load
+}
+
+// MatchSegments finds every pass of each segment in the activities' sessions.
+func (s *Service) MatchSegments(ctx context.Context, activities []activity.Activity, segments []activity.Segment) result.MatchSegments {
+	begin := time.Now()
+
+	res := result.MatchSegments{Segments: make([]result.SegmentMatch, len(segments))}
+	for i := range segments {
+		segment := &segments[i]
+		match := result.SegmentMatch{Name: segment.Name, Length: segment.Length()}
+
+		for j := range activities {
+			for k := range activities[j].Sessions {
+				if err := ctx.Err(); err != nil {
+					return result.MatchSegments{Err: err}
+				}
+
+				efforts := activity.MatchSegment(activities[j].Sessions[k].Records, segment)
+				for l := range efforts {
+					match.Efforts = append(match.Efforts, result.SegmentEffort{
+						ActivityIndex: j,
+						SessionIndex:  k,
+						SegmentEffort: efforts[l],
+					})
+				}
+			}
+		}
+
+		slices.SortStableFunc(match.Efforts, func(a, b result.SegmentEffort) int {
+			return cmp.Compare(a.ElapsedTime, b.ElapsedTime)
+		})
+		res.Segments[i] = match
+	}
+
+	res.MatchSegmentsTook = time.Since(begin)
+
+	return res
+}
+
+// Compare compares the specified sessions over the same route against the first session (the reference).
+func (s *Service) Compare(ctx context.Context, activities []activity.Activity, compareSpec spec.Compare) result.Compare {
+	begin := time.Now()
+
+	if len(compareSpec.Sessions) < 2 {
+		return result.Compare{Err: fmt.Errorf("at least 2 sessions are required, got: %d", len(compareSpec.Sessions))}
+	}
+
+	var alignment activity.Alignment
+	switch compareSpec.Alignment {
+	case "", spec.AlignmentDistance:
+		alignment = activity.AlignByDistance
+	case spec.AlignmentPosition:
+		alignment = activity.AlignByPosition
+	default:
+		return result.Compare{Err: fmt.Errorf("alignment %q is not supported", compareSpec.Alignment)}
+	}
+
+	attempts := make([][]activity.Record, len(compareSpec.Sessions))
+	for i, ref := range compareSpec.Sessions {
+		if ref.ActivityIndex < 0 || ref.ActivityIndex >= len(activities) {
+			return result.Compare{Err: fmt.Errorf("sessions[%d]: activity index %d is out of range", i, ref.ActivityIndex)}
+		}
+		sessions := activities[ref.ActivityIndex].Sessions
+		if ref.SessionIndex < 0 || ref.SessionIndex >= len(sessions) {
+			return result.Compare{Err: fmt.Errorf("sessions[%d]: session index %d is out of range", i, ref.SessionIndex)}
+		}
+		attempts[i] = sessions[ref.SessionIndex].Records
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result.Compare{Err: err}
+	}
+
+	res := result.Compare{
+		Sessions:   compareSpec.Sessions,
+		Comparison: activity.Compare(attempts, alignment, compareSpec.Interval),
+	}
+
+	res.CompareTook = time.Since(begin)
+
+	return res
+}
+
+// aggregateBatchSize is the number of files decoded at once by Aggregate, so only a batch of activities is kept in memory.
+const aggregateBatchSize = 32
+
+// Aggregate decodes the files and aggregates all of their positions into a heatmap at the zoom level and
+// their sessions into totals per sport. Unlike Decode, the activities are not kept, so it's suitable for
+// hundreds of files. Files that can not be decoded are skipped and reported in the result's failures.
+func (s *Service) Aggregate(ctx context.Context, rs []io.Reader, zoom uint8) result.Aggregate {
+	begin := time.Now()
+
+	if len(rs) == 0 {
+		return result.Aggregate{Err: fmt.Errorf("no activity is retrieved")}
+	}
+
+	res := result.Aggregate{Heatmap: activity.NewHeatmap(zoom)}
+	totals := make(map[typedef.Sport]*result.SportTotal)
+	add := func(a *activity.Activity) {
+		counted := make(map[typedef.Sport]struct{})
+		for k := range a.Sessions {
+			ses := &a.Sessions[k]
+			res.Heatmap.Add(ses.Records)
+
+			total, ok := totals[ses.Sport]
+			if !ok {
+				total = &result.SportTotal{Sport: strutils.ToTitle(ses.Sport.String())}
+				totals[ses.Sport] = total
+			}
+			if _, ok := counted[ses.Sport]; !ok {
+				counted[ses.Sport] = struct{}{}
+				total.Activities++
+			}
+			total.Sessions++
+			if ses.TotalDistance != basetype.Uint32Invalid {
+				total.Distance += ses.TotalDistanceScaled()
+			}
+			if ses.TotalElapsedTime != basetype.Uint32Invalid {
+				total.ElapsedTime += ses.TotalElapsedTimeScaled()
+			}
+			if ses.TotalMovingTime != basetype.Uint32Invalid {
+				total.MovingTime += ses.TotalMovingTimeScaled()
+			}
+			if ses.TotalAscent != basetype.Uint16Invalid {
+				total.Ascent += float64(ses.TotalAscent)
+			}
+		}
+		res.Activities++
+	}
+
+	for i := 0; i < len(rs); i += aggregateBatchSize {
+		if err := ctx.Err(); err != nil {
+			return result.Aggregate{Err: err}
+		}
+
+		batch := rs[i:min(i+aggregateBatchSize, len(rs))]
+		activities := make([][]activity.Activity, len(batch))
+		errs := make([]error, len(batch))
+
+		var wg sync.WaitGroup
+		wg.Add(len(batch))
+		for j := range batch {
+			go func(j int) {
+				defer wg.Done()
+				activities[j], errs[j] = s.decode(ctx, batch[j])
+			}(j)
+		}
+		wg.Wait()
+
+		// A file that can not be decoded should not discard the others, it's reported by its index in rs.
+		for j := range batch {
+			if errs[j] != nil {
+				res.Failures = append(res.Failures, result.AggregateFailure{Index: i + j, Err: errs[j]})
+				continue
+			}
+			for k := range activities[j] {
+				add(&activities[j][k])
+			}
+		}
+	}
+
+	res.SportTotals = make([]result.SportTotal, 0, len(totals))
+	for _, total := range totals {
+		res.SportTotals = append(res.SportTotals, *total)
+	}
+	slices.SortFunc(res.SportTotals, func(a, b result.SportTotal) int {
+		return cmp.Compare(a.Sport, b.Sport)
+	})
+
+	res.AggregateTook = time.Since(begin)
+
+	return res
+}
+
+// Report summarizes the activities' sessions grouped by sport and by sport in each week or month.
+func (s *Service) Report(ctx context.Context, activities []activity.Activity, reportSpec spec.Report) result.Report {
+	begin := time.Now()
+
+	if len(activities) == 0 {
+		return result.Report{Err: fmt.Errorf("no activity is retrieved")}
+	}
+
+	period := reportSpec.Period
+	switch period {
+	case "":
+		period = spec.ReportPeriodWeek
+	case spec.ReportPeriodWeek, spec.ReportPeriodMonth:
+	default:
+		return result.Report{Err: fmt.Errorf("report period %q is not supported", reportSpec.Period)}
+	}
+
+	type groupKey struct {
+		sport  typedef.Sport
+		period time.Time
+	}
+	groups := make(map[groupKey]*reportAccumulator)
+	accumulate := func(key groupKey, ses *activity.Session) {
+		acc, ok := groups[key]
+		if !ok {
+			acc = &reportAccumulator{group: result.NewReportGroup(strutils.ToTitle(key.sport.String()), key.period)}
+			groups[key] = acc
+		}
+		acc.add(ses)
+	}
+
+	for i := range activities {
+		if err := ctx.Err(); err != nil {
+			return result.Report{Err: err}
+		}
+
+		a := &activities[i]
+		for j := range a.Sessions {
+			ses := &a.Sessions[j]
+			accumulate(groupKey{sport: ses.Sport}, ses)
+			if ses.StartTime.IsZero() {
+				continue
+			}
+			start := reportPeriodStart(ses.StartTime.Add(time.Duration(a.Timezone)*time.Hour), period)
+			accumulate(groupKey{sport: ses.Sport, period: start}, ses)
+		}
+	}
+
+	res := result.Report{Period: period}
+	for _, acc := range groups {
+		acc.finalize()
+		if acc.group.Period.IsZero() {
+			res.Sports = append(res.Sports, acc.group)
+		} else {
+			res.Periods = append(res.Periods, acc.group)
+		}
+	}
+	slices.SortFunc(res.Sports, func(a, b result.ReportGroup) int {
+		return cmp.Compare(a.Sport, b.Sport)
+	})
+	slices.SortFunc(res.Periods, func(a, b result.ReportGroup) int {
+		if c := a.Period.Compare(b.Period); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Sport, b.Sport)
+	})
+
+	res.ReportTook = time.Since(begin)
+
+	return res
+}
+
+// reportPeriodStart returns the midnight of the Monday of t's week or the first day of t's month.
+func reportPeriodStart(t time.Time, period string) time.Time {
+	if period == spec.ReportPeriodMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// reportAccumulator accumulates sessions into a report group, keeping the time-weighted sums of the averages
+// since aggregator only averages pairwise.
+type reportAccumulator struct {
+	group                       result.ReportGroup
+	heartRateSum, heartRateTime float64
+	powerSum, powerTime         float64
+}
+
+func (r *reportAccumulator) add(ses *activity.Session) {
+	g := result.NewReportGroup(r.group.Sport, r.group.Period)
+	g.NumSessions = 1
+	if ses.TotalDistance != basetype.Uint32Invalid {
+		g.TotalDistance = ses.TotalDistanceScaled()
+		g.MaxDistance = g.TotalDistance
+	}
+	if ses.TotalMovingTime != basetype.Uint32Invalid {
+		g.TotalMovingTime = ses.TotalMovingTimeScaled()
+	}
+	if ses.TotalElapsedTime != basetype.Uint32Invalid {
+		g.TotalElapsedTime = ses.TotalElapsedTimeScaled()
+	}
+	if ses.TotalAscent != basetype.Uint16Invalid {
+		g.TotalAscent = float64(ses.TotalAscent)
+	}
+	if ses.TotalCalories != basetype.Uint16Invalid {
+		g.TotalCalories = float64(ses.TotalCalories)
+	} else if ses.EstimatedCalories > 0 {
+		g.TotalCalories = ses.EstimatedCalories
+	}
+	if ses.AvgSpeed != basetype.Uint16Invalid {
+		g.MaxAvgSpeed = ses.AvgSpeedScaled()
+	} else if ses.EnhancedAvgSpeed != basetype.Uint32Invalid {
+		g.MaxAvgSpeed = ses.EnhancedAvgSpeedScaled()
+	}
+
+	aggregator.Aggregate(&r.group, &g)
+
+	weight := g.TotalMovingTime
+	if math.IsNaN(weight) {
+		weight = g.TotalElapsedTime
+	}
+	if math.IsNaN(weight) || weight == 0 {
+		return
+	}
+	if ses.AvgHeartRate != basetype.Uint8Invalid {
+		r.heartRateSum += float64(ses.AvgHeartRate) * weight
+		r.heartRateTime += weight
+	}
+	if ses.AvgPower != basetype.Uint16Invalid {
+		r.powerSum += float64(ses.AvgPower) * weight
+		r.powerTime += weight
+	}
+}
+
+// finalize replaces the group's pairwise averages with the time-weighted averages.
+func (r *reportAccumulator) finalize() {
+	r.group.AvgHeartRate, r.group.AvgPower = math.NaN(), math.NaN()
+	if r.heartRateTime > 0 {
+		r.group.AvgHeartRate = r.heartRateSum / r.heartRateTime
+	}
+	if r.powerTime > 0 {
+		r.group.AvgPower = r.powerSum / r.powerTime
+	}
+}
+
 func (s *Service) ManufacturerList() result.ManufacturerList {
 	manufacturers := make([]activity.Manufacturer, 0, len(s.manufacturers))
 	for _, v := range s.manufacturers {
@@ -734,7 +1912,7 @@ func (s *Service) SportList() result.SportList {
 			ID:   v,
 			Name: strutils.ToTitle(v.String()),
 		}
-		sport.ToleranceMovingSpeed = activity.ToleranceMovingSpeed(sport.ID)
+		sport.ToleranceMovingSpeed = s.preprocessor.Summarizer().ToleranceMovingSpeed(sport.ID)
 		sports = append(sports, sport)
 	}
 
@@ -0,0 +1,468 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/proto"
+	"github.com/openivity/activity-service/activity"
+	"github.com/openivity/activity-service/service/result"
+	"github.com/openivity/activity-service/service/spec"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newActivity creates a single session activity from 1s records, fill sets the fields of the i-th record.
+func newActivity(s *Service, sport typedef.Sport, n int, fill func(i int, rec *mesgdef.Record)) activity.Activity {
+	records := make([]activity.Record, n)
+	for i := range records {
+		rec := mesgdef.NewRecord(nil).SetTimestamp(t0.Add(time.Duration(i) * time.Second))
+		if fill != nil {
+			fill(i, rec)
+		}
+		records[i] = activity.CreateRecord(rec)
+	}
+
+	lap := activity.NewLapFromRecords(records, sport, s.preprocessor.Summarizer())
+	ses := activity.NewSessionFromLaps([]activity.Lap{lap})
+	ses.Laps = []activity.Lap{lap}
+	ses.Records = records
+
+	a := activity.CreateActivity()
+	a.Sessions = []activity.Session{ses}
+	return a
+}
+
+// newEncodeSpec creates an encode spec of the activities with markers covering all of their records.
+func newEncodeSpec(toolMode spec.EncodeToolMode, activities ...activity.Activity) spec.Encode {
+	encodeSpec := spec.Encode{
+		ToolMode:       toolMode,
+		TargetFileType: spec.FileTypeGPX,
+		Activities:     activities,
+	}
+	for i := range activities {
+		for j := range activities[i].Sessions {
+			marker := spec.EncodeMarker{EndN: len(activities[i].Sessions[j].Records) - 1}
+			encodeSpec.TrimMarkers = append(encodeSpec.TrimMarkers, marker)
+			encodeSpec.ConcealMarkers = append(encodeSpec.ConcealMarkers, marker)
+			encodeSpec.Sports = append(encodeSpec.Sports, activities[i].Sessions[j].Sport.String())
+		}
+	}
+	return encodeSpec
+}
+
+func TestPreprocessEncodeSimplifyLast(t *testing.T) {
+	s := New(activity.NewPreprocessor(), nil, nil, nil, nil)
+
+	tt := []struct {
+		name     string
+		simplify spec.SimplifyMethod
+	}{
+		{name: "no simplification", simplify: spec.SimplifyNone},
+		{name: "resample", simplify: spec.SimplifyResample},
+		{name: "douglas-peucker", simplify: spec.SimplifyDouglasPeucker},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// 10 minutes at 250W on a straight line: 250 * 600 / 4184 / 0.24 ≈ 149 kcal.
+			a := newActivity(s, typedef.SportCycling, 601, func(i int, rec *mesgdef.Record) {
+				rec.SetPower(250).
+					SetPositionLatDegrees(0.001 + float64(i)*0.0001).
+					SetPositionLongDegrees(10)
+			})
+			encodeSpec := newEncodeSpec(spec.ToolModeEdit, a)
+			encodeSpec.Simplify = tc.simplify
+			encodeSpec.Interval = 120
+			encodeSpec.Tolerance = 1
+
+			activities, _, err := s.preprocessEncode(encodeSpec)
+			if err != nil {
+				t.Fatalf("expected nil, got: %v", err)
+			}
+
+			ses := &activities[0].Sessions[0]
+			if tc.simplify != spec.SimplifyNone && len(ses.Records) >= 601 {
+				t.Fatalf("expected records to be simplified, got: %d records", len(ses.Records))
+			}
+			if ses.TotalCalories != 149 {
+				t.Fatalf("expected total calories: 149, got: %d", ses.TotalCalories)
+			}
+			if ses.Laps[0].TotalCalories != 149 {
+				t.Fatalf("expected lap total calories: 149, got: %d", ses.Laps[0].TotalCalories)
+			}
+			if ses.NormalizedPower != 250 {
+				t.Fatalf("expected normalized power: 250, got: %d", ses.NormalizedPower)
+			}
+		})
+	}
+}
+
+func TestPreprocessEncodeRemovePauses(t *testing.T) {
+	s := New(activity.NewPreprocessor(), nil, nil, nil, nil)
+
+	// Moving from 0s to 29s, stationary from 30s to 59s and moving again from 60s to 99s.
+	newMovingActivity := func() activity.Activity {
+		var lat float64 = 0.001
+		return newActivity(s, typedef.SportCycling, 100, func(i int, rec *mesgdef.Record) {
+			speed := 5.0
+			if i >= 30 && i < 60 {
+				speed = 0
+			} else {
+				lat += 0.00005
+			}
+			rec.SetSpeedScaled(speed).SetPositionLatDegrees(lat).SetPositionLongDegrees(10)
+		})
+	}
+	newTimerEvent := func(offset int, eventType typedef.EventType) proto.Message {
+		return mesgdef.NewEvent(nil).
+			SetTimestamp(t0.Add(time.Duration(offset) * time.Second)).
+			SetEvent(typedef.EventTimer).
+			SetEventType(eventType).
+			ToMesg(nil)
+	}
+
+	tt := []struct {
+		name           string
+		method         spec.PauseRemovalMethod
+		simplify       spec.SimplifyMethod
+		events         []proto.Message
+		expectedLen    int
+		expectedEvents int
+		expectedTimer  uint32
+	}{
+		{
+			name:          "compress",
+			method:        spec.PauseRemovalCompress,
+			expectedLen:   70,
+			expectedTimer: 70000,
+		},
+		{
+			name:          "compress detects pauses before simplifying",
+			method:        spec.PauseRemovalCompress,
+			simplify:      spec.SimplifyDouglasPeucker,
+			expectedLen:   2,
+			expectedTimer: 70000,
+		},
+		{
+			name:           "timer events",
+			method:         spec.PauseRemovalTimerEvents,
+			expectedLen:    100,
+			expectedEvents: 2,
+			expectedTimer:  70000,
+		},
+		{
+			name:   "timer events already recorded by the device",
+			method: spec.PauseRemovalTimerEvents,
+			events: []proto.Message{
+				newTimerEvent(31, typedef.EventTypeStop),
+				newTimerEvent(59, typedef.EventTypeStart),
+			},
+			expectedLen:    100,
+			expectedEvents: 2,
+			expectedTimer:  72000,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newMovingActivity()
+			a.UnrelatedMessages = append(a.UnrelatedMessages, tc.events...)
+			encodeSpec := newEncodeSpec(spec.ToolModeRemovePauses, a)
+			encodeSpec.PauseRemoval = tc.method
+			encodeSpec.Simplify = tc.simplify
+			encodeSpec.Tolerance = 1
+
+			activities, _, err := s.preprocessEncode(encodeSpec)
+			if err != nil {
+				t.Fatalf("expected nil, got: %v", err)
+			}
+
+			ses := &activities[0].Sessions[0]
+			if len(ses.Records) != tc.expectedLen {
+				t.Fatalf("expected: %d records, got: %d", tc.expectedLen, len(ses.Records))
+			}
+			if n := len(activities[0].UnrelatedMessages); n != tc.expectedEvents {
+				t.Fatalf("expected: %d events, got: %d", tc.expectedEvents, n)
+			}
+			if ses.TotalTimerTime != tc.expectedTimer {
+				t.Fatalf("expected total timer time: %d, got: %d", tc.expectedTimer, ses.TotalTimerTime)
+			}
+		})
+	}
+}
+
+func TestPreprocessEncodeFilledGaps(t *testing.T) {
+	s := New(activity.NewPreprocessor(), nil, nil, nil, nil)
+
+	// Each activity has a 20s gap after 30s of records, resulting 19 interpolated records.
+	newGappedActivity := func(start time.Time) activity.Activity {
+		return newActivity(s, typedef.SportCycling, 60, func(i int, rec *mesgdef.Record) {
+			offset := i
+			if i >= 30 {
+				offset += 19
+			}
+			rec.SetTimestamp(start.Add(time.Duration(offset) * time.Second)).
+				SetDistanceScaled(float64(offset) * 5).
+				SetSpeedScaled(5)
+		})
+	}
+
+	tt := []struct {
+		name     string
+		toolMode spec.EncodeToolMode
+		simplify spec.SimplifyMethod
+		expected []int // number of gaps of each file.
+	}{
+		{name: "edit", toolMode: spec.ToolModeEdit, expected: []int{1, 1}},
+		{name: "combine", toolMode: spec.ToolModeCombine, expected: []int{2}},
+		{name: "split per session", toolMode: spec.ToolModeSplitPerSession, expected: []int{1, 1}},
+		{name: "remove pauses", toolMode: spec.ToolModeRemovePauses, expected: []int{1, 1}},
+		{name: "simplified", toolMode: spec.ToolModeEdit, simplify: spec.SimplifyResample, expected: []int{1, 1}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			encodeSpec := newEncodeSpec(tc.toolMode, newGappedActivity(t0), newGappedActivity(t0.Add(time.Hour)))
+			encodeSpec.FillGaps = spec.GapFillBlank
+			encodeSpec.Simplify = tc.simplify
+			encodeSpec.Interval = 30
+
+			activities, filledGaps, err := s.preprocessEncode(encodeSpec)
+			if err != nil {
+				t.Fatalf("expected nil, got: %v", err)
+			}
+			if len(filledGaps) != len(activities) || len(filledGaps) != len(tc.expected) {
+				t.Fatalf("expected: %d files, got: %d activities and %d filled gaps",
+					len(tc.expected), len(activities), len(filledGaps))
+			}
+			for i := range filledGaps {
+				if len(filledGaps[i]) != tc.expected[i] {
+					t.Fatalf("[%d] expected: %d gaps, got: %d", i, tc.expected[i], len(filledGaps[i]))
+				}
+				for _, gap := range filledGaps[i] {
+					if gap.Records != 19 {
+						t.Fatalf("[%d] expected: 19 records, got: %d", i, gap.Records)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodePreprocessSettingsKeepAthlete(t *testing.T) {
+	s := New(activity.NewPreprocessor(), nil, nil, nil, nil)
+
+	// Decode with the athlete's profile.
+	decodeSettings := spec.Preprocess{Athlete: spec.Athlete{Weight: 70, Age: 30}}
+	if err := s.Configure(decodeSettings); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	// Encode without settings, 10 minutes at 150 bpm.
+	a := newActivity(s, typedef.SportRunning, 601, func(i int, rec *mesgdef.Record) { rec.SetHeartRate(150) })
+	encodeSpec := newEncodeSpec(spec.ToolModeEdit, a)
+	if err := s.Configure(encodeSpec.PreprocessSettings(decodeSettings.Athlete)); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	activities, _, err := s.preprocessEncode(encodeSpec)
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	// (-55.0969 + 0.6309*150 + 0.1988*70 + 0.2017*30) / 4.184 * 10 ≈ 142 kcal.
+	ses := &activities[0].Sessions[0]
+	if ses.TotalCalories != 142 {
+		t.Fatalf("expected total calories: 142, got: %d", ses.TotalCalories)
+	}
+}
+
+func TestFillPowerMetricsOnlyCycling(t *testing.T) {
+	s := New(activity.NewPreprocessor(), nil, nil, nil, nil)
+	if err := s.Configure(spec.Preprocess{Athlete: spec.Athlete{FunctionalThresholdPower: 250}}); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	tt := []struct {
+		sport    typedef.Sport
+		expected uint16 // normalized power
+	}{
+		{sport: typedef.SportCycling, expected: 250},
+		{sport: typedef.SportRunning, expected: basetype.Uint16Invalid},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.sport.String(), func(t *testing.T) {
+			a := newActivity(s, tc.sport, 601, func(i int, rec *mesgdef.Record) { rec.SetPower(250) })
+			s.fillPowerMetrics(&a)
+
+			ses := &a.Sessions[0]
+			if ses.NormalizedPower != tc.expected {
+				t.Fatalf("expected normalized power: %d, got: %d", tc.expected, ses.NormalizedPower)
+			}
+			if lap := &ses.Laps[0]; lap.NormalizedPower != tc.expected {
+				t.Fatalf("expected lap normalized power: %d, got: %d", tc.expected, lap.NormalizedPower)
+			}
+			if valid := ses.TrainingStressScore != basetype.Uint16Invalid; valid != (tc.sport == typedef.SportCycling) {
+				t.Fatalf("expected training stress score only for cycling, got: %d", ses.TrainingStressScore)
+			}
+		})
+	}
+}
+
+func TestPreprocessEncodeAutoTrim(t *testing.T) {
+	s := New(activity.NewPreprocessor(), nil, nil, nil, nil)
+
+	tt := []struct {
+		name        string
+		speeds      []float64
+		expectedLen int
+	}{
+		{name: "stationary head and tail", speeds: []float64{0, 0, 3, 3, 3, 0, 0}, expectedLen: 5},
+		{name: "all stationary", speeds: []float64{0, 0, 0}, expectedLen: 3},
+		{name: "single moving record", speeds: []float64{3}, expectedLen: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newActivity(s, typedef.SportRunning, len(tc.speeds), func(i int, rec *mesgdef.Record) {
+				rec.SetSpeedScaled(tc.speeds[i])
+			})
+			encodeSpec := newEncodeSpec(spec.ToolModeEdit, a)
+			encodeSpec.AutoTrim = true
+
+			activities, _, err := s.preprocessEncode(encodeSpec)
+			if err != nil {
+				t.Fatalf("expected nil, got: %v", err)
+			}
+			if n := len(activities[0].Sessions[0].Records); n != tc.expectedLen {
+				t.Fatalf("expected: %d records, got: %d", tc.expectedLen, n)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	s := New(activity.NewPreprocessor(), nil, nil, nil, nil)
+
+	newReportActivity := func(sport typedef.Sport, startTime time.Time, timezone int8, distance, movingTime float64,
+		heartRate uint8, power uint16) activity.Activity {
+		ses := activity.CreateSession(mesgdef.NewSession(nil).
+			SetSport(sport).
+			SetStartTime(startTime).
+			SetTotalDistanceScaled(distance).
+			SetTotalMovingTimeScaled(movingTime).
+			SetTotalElapsedTimeScaled(movingTime).
+			SetAvgHeartRate(heartRate).
+			SetAvgPower(power))
+		a := activity.CreateActivity()
+		a.Timezone = timezone
+		a.Sessions = []activity.Session{ses}
+		return a
+	}
+
+	activities := []activity.Activity{
+		newReportActivity(typedef.SportRunning, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), 0, 10000, 3600, 150, basetype.Uint16Invalid),
+		newReportActivity(typedef.SportCycling, time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), 0, 40000, 3600, basetype.Uint8Invalid, 200),
+		// Sunday 23:30 UTC is Monday 00:30 in local time, the next week.
+		newReportActivity(typedef.SportRunning, time.Date(2024, 1, 7, 23, 30, 0, 0, time.UTC), 1, 5000, 1800, 120, basetype.Uint16Invalid),
+	}
+
+	type group struct {
+		sport         string
+		period        time.Time
+		numSessions   uint32
+		totalDistance float64
+		maxDistance   float64
+		avgHeartRate  float64
+	}
+	sports := []group{
+		{sport: "Cycling", numSessions: 1, totalDistance: 40000, maxDistance: 40000, avgHeartRate: math.NaN()},
+		{sport: "Running", numSessions: 2, totalDistance: 15000, maxDistance: 10000, avgHeartRate: (150*3600 + 120*1800) / 5400.0},
+	}
+
+	tt := []struct {
+		name            string
+		period          string
+		expectedPeriods []group
+		expectedErr     bool
+	}{
+		{
+			name:   "week is the default",
+			period: "",
+			expectedPeriods: []group{
+				{sport: "Cycling", period: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), numSessions: 1, totalDistance: 40000, maxDistance: 40000, avgHeartRate: math.NaN()},
+				{sport: "Running", period: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), numSessions: 1, totalDistance: 10000, maxDistance: 10000, avgHeartRate: 150},
+				{sport: "Running", period: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), numSessions: 1, totalDistance: 5000, maxDistance: 5000, avgHeartRate: 120},
+			},
+		},
+		{
+			name:   "month",
+			period: spec.ReportPeriodMonth,
+			expectedPeriods: []group{
+				{sport: "Cycling", period: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), numSessions: 1, totalDistance: 40000, maxDistance: 40000, avgHeartRate: math.NaN()},
+				{sport: "Running", period: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), numSessions: 2, totalDistance: 15000, maxDistance: 10000, avgHeartRate: (150*3600 + 120*1800) / 5400.0},
+			},
+		},
+		{
+			name:        "unsupported period",
+			period:      "year",
+			expectedErr: true,
+		},
+	}
+
+	equal := func(a, b float64) bool {
+		if math.IsNaN(a) || math.IsNaN(b) {
+			return math.IsNaN(a) && math.IsNaN(b)
+		}
+		return math.Abs(a-b) < 1e-9
+	}
+	check := func(t *testing.T, groups []result.ReportGroup, expected []group) {
+		if len(groups) != len(expected) {
+			t.Fatalf("expected: %d groups, got: %d", len(expected), len(groups))
+		}
+		for i, g := range groups {
+			e := expected[i]
+			if g.Sport != e.sport || !g.Period.Equal(e.period) || g.NumSessions != e.numSessions ||
+				!equal(g.TotalDistance, e.totalDistance) || !equal(g.MaxDistance, e.maxDistance) ||
+				!equal(g.AvgHeartRate, e.avgHeartRate) {
+				t.Fatalf("[%d] expected: %+v, got: %+v", i, e, g)
+			}
+		}
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := s.Report(context.Background(), activities, spec.Report{Period: tc.period})
+			if (res.Err != nil) != tc.expectedErr {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, res.Err)
+			}
+			if tc.expectedErr {
+				return
+			}
+			check(t, res.Sports, sports)
+			check(t, res.Periods, tc.expectedPeriods)
+		})
+	}
+}
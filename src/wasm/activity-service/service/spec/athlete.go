@@ -0,0 +1,48 @@
+// Copyright (C) 2023 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package spec
+
+// Athlete is athlete's profile used for analysis such as training zones. Zero values mean not specified.
+type Athlete struct {
+	MaxHeartRate        uint8   `json:"maxHeartRate"`        // in bpm
+	RestingHeartRate    uint8   `json:"restingHeartRate"`    // in bpm
+	ThresholdHeartRate  uint8   `json:"thresholdHeartRate"`  // Lactate threshold heart rate in bpm.
+	HeartRateZoneMethod string  `json:"heartRateZoneMethod"` // See HeartRateZone* constants.
+	HeartRateZones      []uint8 `json:"heartRateZones"`      // Only for HeartRateZoneCustom: zones' high boundaries in bpm.
+
+	FunctionalThresholdPower uint16 `json:"functionalThresholdPower"` // FTP in watts.
+	CriticalPower            uint16 `json:"criticalPower"`            // CP in watts, used with WPrime for W' balance.
+	WPrime                   uint32 `json:"wPrime"`                   // Anaerobic work capacity above CP in joules.
+	Gender                   string `json:"gender"`                   // Either "male" or "female", used by TRIMP weighting factor and calorie estimation. Default is "male".
+
+	Weight float64 `json:"weight"` // in kg, used by calorie estimation.
+	Age    uint8   `json:"age"`    // in years, used by calorie estimation.
+}
+
+// HeartRateZone* are the names of method to determine heart rate zones. Empty string means HeartRateZoneCustom
+// if HeartRateZones is specified, otherwise no heart rate zones will be calculated.
+const (
+	HeartRateZoneCustom       = "custom"
+	HeartRateZoneMaxHeartRate = "maxHeartRate"
+	HeartRateZoneThreshold    = "thresholdHeartRate"
+	HeartRateZoneReserve      = "heartRateReserve"
+)
+
+// Gender* are athlete's gender.
+const (
+	GenderMale   = "male"
+	GenderFemale = "female"
+)
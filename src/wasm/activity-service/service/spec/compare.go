@@ -0,0 +1,35 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package spec
+
+// Alignment methods of Compare.
+const (
+	AlignmentDistance = "distance"
+	AlignmentPosition = "position"
+)
+
+// Compare is the specification of comparing attempts over the same route.
+type Compare struct {
+	Sessions  []CompareSession `json:"sessions"`  // The first session is the reference, at least 2 sessions.
+	Alignment string           `json:"alignment"` // Either "distance" or "position", empty means "distance".
+	Interval  float64          `json:"interval"`  // Distance between samples in meters, 0 means default (100m), at least 1m.
+}
+
+// CompareSession refers to a session of the decoded activities.
+type CompareSession struct {
+	ActivityIndex int `json:"activityIndex"`
+	SessionIndex  int `json:"sessionIndex"`
+}
@@ -21,16 +21,35 @@ import (
 )
 
 type Encode struct {
-	ToolMode       EncodeToolMode       `json:"toolMode"`       // Selected Encode Mode
-	TargetFileType FileType             `json:"targetFileType"` // Either fit, gpx, or tcx
-	ManufacturerID typedef.Manufacturer `json:"manufacturerId"` // Only for FIT FileType
-	ProductID      uint16               `json:"productId"`      // Only for FIT FileType
-	DeviceName     string               `json:"deviceName"`     // Only for non-FIT FileType
-	Sports         []string             `json:"sports"`         // Change sports
-	TrimMarkers    []EncodeMarker       `json:"trimMarkers"`    // Trim markers; If specified, len should match len(sessions).
-	ConcealMarkers []EncodeMarker       `json:"concealMarkers"` // Conceal markers; If specified, len should match len(sessions).
-	RemoveFields   []string             `json:"removeFields"`   // Remove spefified fields from all records.
-	Activities     []activity.Activity  `json:"-"`
+	ToolMode          EncodeToolMode       `json:"toolMode"`          // Selected Encode Mode
+	TargetFileType    FileType             `json:"targetFileType"`    // Either fit, gpx, or tcx
+	ManufacturerID    typedef.Manufacturer `json:"manufacturerId"`    // Only for FIT FileType
+	ProductID         uint16               `json:"productId"`         // Only for FIT FileType
+	DeviceName        string               `json:"deviceName"`        // Only for non-FIT FileType
+	Sports            []string             `json:"sports"`            // Change sports
+	TrimMarkers       []EncodeMarker       `json:"trimMarkers"`       // Trim markers; If specified, len should match len(sessions).
+	AutoTrim          bool                 `json:"autoTrim"`          // Trim stationary head and tail of each session, TrimMarkers are ignored.
+	ConcealMarkers    []EncodeMarker       `json:"concealMarkers"`    // Conceal markers; If specified, len should match len(sessions).
+	RemoveFields      []string             `json:"removeFields"`      // Remove spefified fields from all records.
+	PauseRemoval      PauseRemovalMethod   `json:"pauseRemoval"`      // Only for ToolModeRemovePauses
+	UseSmoothed       bool                 `json:"useSmoothed"`       // Write smoothed positions and altitude instead of the raw ones.
+	Simplify          SimplifyMethod       `json:"simplify"`          // Reduce the number of records.
+	Tolerance         float64              `json:"tolerance"`         // Only for SimplifyDouglasPeucker, in meters.
+	Interval          uint32               `json:"interval"`          // Only for SimplifyResample, in seconds.
+	FillGaps          GapFillMethod        `json:"fillGaps"`          // Fill missing records in 1s record stream.
+	FillGapsThreshold uint32               `json:"fillGapsThreshold"` // Only fill gaps longer than this in seconds, 0 means default (10s).
+	Preprocess        *Preprocess          `json:"preprocess"`        // If specified, reprocess records using these settings.
+	WriteSplits       bool                 `json:"writeSplits"`       // Only for FIT FileType, write distance-based splits as split and split_summary.
+	Activities        []activity.Activity  `json:"-"`
+}
+
+// PreprocessSettings returns the preprocessing settings of the encode request, requests without settings use the
+// default settings. The athlete's profile is not an encode setting, so the given athlete is kept in that case.
+func (e *Encode) PreprocessSettings(athlete Athlete) Preprocess {
+	if e.Preprocess == nil {
+		return Preprocess{Athlete: athlete}
+	}
+	return *e.Preprocess
 }
 
 type EncodeToolMode byte
@@ -40,6 +59,7 @@ const (
 	ToolModeEdit
 	ToolModeCombine
 	ToolModeSplitPerSession
+	ToolModeRemovePauses
 )
 
 func (e EncodeToolMode) String() string {
@@ -50,11 +70,39 @@ func (e EncodeToolMode) String() string {
 		return "combine"
 	case ToolModeSplitPerSession:
 		return "split"
+	case ToolModeRemovePauses:
+		return "nopause"
 	default:
 		return "unknown"
 	}
 }
 
+// PauseRemovalMethod is the method to remove stationary periods from the activity.
+type PauseRemovalMethod byte
+
+const (
+	PauseRemovalCompress    PauseRemovalMethod = iota // Remove stationary records and shift later timestamps back.
+	PauseRemovalTimerEvents                           // Keep the records, insert timer stop/start events instead (FIT only).
+)
+
+// SimplifyMethod is the method to reduce the number of records.
+type SimplifyMethod byte
+
+const (
+	SimplifyNone           SimplifyMethod = iota
+	SimplifyDouglasPeucker                // Remove records that are not significant to the shape of the track.
+	SimplifyResample                      // Resample records into 1 record per interval.
+)
+
+// GapFillMethod is the method to fill sensor values of interpolated records when filling the gaps.
+type GapFillMethod byte
+
+const (
+	GapFillNone  GapFillMethod = iota
+	GapFillBlank               // Leave sensor values (heart rate, cadence, power, etc.) blank.
+	GapFillHold                // Hold sensor values from the record before the gap.
+)
+
 type FileType byte
 
 const (
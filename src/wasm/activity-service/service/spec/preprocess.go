@@ -0,0 +1,55 @@
+// Copyright (C) 2023 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package spec
+
+// Preprocess is preprocessing settings carried by decode and encode requests. Zero values use the defaults.
+type Preprocess struct {
+	SmoothingElevationDistance float64            `json:"smoothingElevationDistance"` // Smoothing window in meters.
+	CalculateGradeDistance     float64            `json:"calculateGradeDistance"`     // Grade window in meters.
+	MaxAcceleration            float64            `json:"maxAcceleration"`            // Max acceleration (m/s²) before a position is considered as GPS error.
+	SmoothingPosition          bool               `json:"smoothingPosition"`          // Smooth positions and elevation using Kalman filter.
+	ProcessNoise               float64            `json:"processNoise"`               // Only for SmoothingPosition, in m/s².
+	MeasurementNoise           float64            `json:"measurementNoise"`           // Only for SmoothingPosition, in meters.
+	ToleranceMovingSpeeds      map[string]float64 `json:"toleranceMovingSpeeds"`      // Moving speed threshold (m/s) per sport name.
+	DisabledStages             []string           `json:"disabledStages"`             // Preprocessing stages to skip, see PreprocessStage* constants.
+	ElevationGain              string             `json:"elevationGain"`              // Elevation gain algorithm, see ElevationGain* constants.
+	ElevationGainThreshold     float64            `json:"elevationGainThreshold"`     // Only for ElevationGainHysteresis, in meters.
+	Athlete                    Athlete            `json:"athlete"`                    // Athlete's profile for analysis.
+	SplitUnit                  string             `json:"splitUnit"`                  // Either "km" or "mi", default is "km".
+	SplitLength                float64            `json:"splitLength"`                // Split length in SplitUnit, default is 1.
+	MinStopDuration            uint32             `json:"minStopDuration"`            // Minimum pause duration in seconds to be reported as a stop, default is 60.
+}
+
+// PreprocessStage* are the names of preprocessing stage that can be disabled.
+const (
+	PreprocessStageRemoveGPSOutliers  = "removeGPSOutliers"
+	PreprocessStageCorrectElevation   = "correctElevation"
+	PreprocessStageSmoothingElevation = "smoothingElevation"
+	PreprocessStageCalculateGrade     = "calculateGrade"
+)
+
+// ElevationGain* are the names of algorithm to calculate total ascent and descent. Empty string means ElevationGainSmoothed.
+const (
+	ElevationGainSmoothed   = "smoothed"
+	ElevationGainRaw        = "raw"
+	ElevationGainHysteresis = "hysteresis"
+)
+
+// SplitUnit* are the units of split length.
+const (
+	SplitUnitKilometer = "km"
+	SplitUnitMile      = "mi"
+)
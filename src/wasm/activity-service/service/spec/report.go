@@ -0,0 +1,34 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package spec
+
+// Report periods.
+const (
+	ReportPeriodWeek  = "week"
+	ReportPeriodMonth = "month"
+)
+
+// Report formats.
+const (
+	ReportFormatJSON = "json"
+	ReportFormatCSV  = "csv"
+)
+
+// Report is the specification of summary report across decoded activities.
+type Report struct {
+	Period string `json:"period"` // Either "week" or "month", empty means "week".
+	Format string `json:"format"` // Either "json" or "csv", empty means "json".
+}
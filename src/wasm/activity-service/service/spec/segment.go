@@ -0,0 +1,23 @@
+// Copyright (C) 2024 Openivity
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package spec
+
+// Segment is a user-defined segment to be written into the segment library file.
+type Segment struct {
+	Name   string       `json:"name"`
+	Points [][2]float64 `json:"points"` // [lat, long] in degrees, the first and the last are the start and the end.
+	Radius float64      `json:"radius"` // Radius in meters around the points, zero means the default radius.
+}
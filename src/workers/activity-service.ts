@@ -28,7 +28,7 @@ onmessage = async (e) => {
       break
     case 'decode': {
       // @ts-ignore
-      const result = decode(e.data.input)
+      const result = decode(e.data.input, e.data.settings ? JSON.stringify(e.data.settings) : undefined)
       const resultJson = JSON.parse(result)
       postMessage({
         type: e.data.type,
@@ -48,6 +48,16 @@ onmessage = async (e) => {
       })
       break
     }
+    case 'analyze': {
+      // @ts-ignore
+      const result = analyze()
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
     case 'manufacturerList': {
       // @ts-ignore
       const manufacturers = JSON.parse(manufacturerList(e.data.input))
@@ -68,6 +78,76 @@ onmessage = async (e) => {
       })
       break
     }
+    case 'loadElevationTiles': {
+      // @ts-ignore
+      const result = loadElevationTiles(e.data.input.names, e.data.input.tiles, e.data.input.fillOnly)
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
+    case 'matchSegments': {
+      // @ts-ignore
+      const result = matchSegments(e.data.input.files, e.data.input.radius)
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
+    case 'encodeSegments': {
+      // @ts-ignore
+      const result = encodeSegments(JSON.stringify(e.data.input))
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
+    case 'compare': {
+      // @ts-ignore
+      const result = compare(JSON.stringify(e.data.input))
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
+    case 'aggregate': {
+      // @ts-ignore
+      const result = aggregate(e.data.input.files, e.data.input.zoom)
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
+    case 'report': {
+      // @ts-ignore
+      const result = report(JSON.stringify(e.data.input))
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
+    case 'exportRecords': {
+      // @ts-ignore
+      const result = exportRecords()
+      postMessage({
+        type: e.data.type,
+        result: JSON.parse(result),
+        elapsed: new Date().getTime() - begin.getTime()
+      })
+      break
+    }
     case 'shutdown':
       // @ts-ignore
       shutdown()